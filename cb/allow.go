@@ -0,0 +1,60 @@
+package cb
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+)
+
+// errReportedFailure stands in for the error of a failure reported through
+// Allow, which has none of its own
+var errReportedFailure = errors.New("reported failure")
+
+// Allow is the two-phase alternative to Call, for code that runs its own
+// request, such as a stream or a long-lived connection. It reports whether the
+// request may proceed, admitting it exactly like Call would. If it may, the
+// caller runs the request and then calls report with whether it succeeded;
+// until then the request counts as in flight. Reports after the first are
+// ignored. When the request isn't allowed, report is nil.
+//
+// The breaker's timeout, failure classifier and fallback don't apply, since
+// the breaker neither runs the request nor sees its error.
+func (cb *circuitBreaker) Allow() (bool, func(success bool)) {
+	c := &call{ctx: context.Background(), outcome: OutcomeRejected, classified: true, isFailure: isAnyError}
+
+	cb.mu.Lock()
+	c.log = cb.log()
+	_, err := cb.dispatch(c)
+	if !c.admitted {
+		if hook := cb.onCallComplete; hook != nil {
+			cb.afterUnlock(func() { hook(0, OutcomeRejected, err) })
+		}
+		cb.unlock()
+		return false, nil
+	}
+	cb.unlock()
+
+	start := c.clock.Now()
+	var once sync.Once
+	return true, func(success bool) {
+		once.Do(func() { cb.report(c, start, success) })
+	}
+}
+
+// report records the outcome of a request admitted by Allow
+func (cb *circuitBreaker) report(c *call, start time.Time, success bool) {
+	c.duration = c.clock.Now().Sub(start)
+	var res callResult
+	if !success {
+		res.err = errReportedFailure
+	}
+
+	cb.mu.Lock()
+	_, err := cb.finish(c, res, true)
+	if hook := cb.onCallComplete; hook != nil {
+		d, outcome := c.duration, c.outcome
+		cb.afterUnlock(func() { hook(d, outcome, err) })
+	}
+	cb.unlock()
+}
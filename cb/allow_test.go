@@ -0,0 +1,69 @@
+package cb
+
+import (
+	"testing"
+	"time"
+)
+
+func TestCircuitBreaker_Allow(t *testing.T) {
+	t.Parallel()
+
+	clock := newFakeClock()
+	cb := NewCircuitBreaker(2, time.Minute, 1, time.Second, WithClock(clock))
+
+	allowed, report := cb.Allow()
+	if !allowed {
+		t.Fatalf("expected the closed circuit to allow the request")
+	}
+	if got := cb.Stats().InFlight; got != 1 {
+		t.Fatalf("expected the request to be in flight until reported, got %d", got)
+	}
+	report(true)
+	if got := cb.Stats(); got.InFlight != 0 || got.Successes != 1 {
+		t.Fatalf("expected 1 success and nothing in flight, got %+v", got)
+	}
+
+	for i := 0; i < 2; i++ {
+		_, report = cb.Allow()
+		report(false)
+		report(false)
+	}
+	if got := cb.Stats().Failures; got != 2 {
+		t.Fatalf("expected repeated reports to be ignored, got %d failures", got)
+	}
+	if cb.State() != Open {
+		t.Fatalf("expected reported failures to trip the circuit, got %s", cb.State())
+	}
+
+	allowed, report = cb.Allow()
+	if allowed || report != nil {
+		t.Fatalf("expected the open circuit to reject the request")
+	}
+	if got := cb.Stats().Rejections; got != 1 {
+		t.Fatalf("expected 1 rejection, got %d", got)
+	}
+
+	clock.Advance(time.Minute + time.Millisecond)
+	allowed, report = cb.Allow()
+	if !allowed {
+		t.Fatalf("expected the request after recovery to be allowed as a probe")
+	}
+	report(true)
+	if cb.State() != Closed {
+		t.Fatalf("expected the reported probe success to close the circuit, got %s", cb.State())
+	}
+}
+
+func TestCircuitBreaker_AllowIgnoresClassifier(t *testing.T) {
+	t.Parallel()
+
+	cb := NewCircuitBreaker(1, time.Minute, 1, time.Second, WithIsFailure(func(error) bool {
+		return false
+	}))
+
+	_, report := cb.Allow()
+	report(false)
+	if cb.State() != Open {
+		t.Fatalf("expected a reported failure to count regardless of the classifier, got %s", cb.State())
+	}
+}
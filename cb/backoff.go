@@ -0,0 +1,45 @@
+package cb
+
+import (
+	"math"
+	"time"
+)
+
+// backoff stretches the recovery time of a dependency that keeps failing its
+// half-open probes
+type backoff struct {
+	factor float64       // Multiplier applied per consecutive failed probe round
+	max    time.Duration // Ceiling of the stretched recovery time
+}
+
+// WithBackoff multiplies the recovery time by factor every time the half-open
+// circuit reopens in a row, or a two-state retry fails, up to max, so that a
+// flapping dependency is probed less and less often instead of on a tight
+// fixed cadence. The recovery time goes back to normal once the circuit
+// closes. A factor of 1 or less disables the backoff.
+func WithBackoff(factor float64, max time.Duration) Option {
+	return func(cb *circuitBreaker) {
+		cb.backoff = nil
+		if factor > 1 {
+			cb.backoff = &backoff{factor: factor, max: max}
+		}
+	}
+}
+
+// backedOff returns the recovery time stretched for the consecutive reopens
+// so far
+func (cb *circuitBreaker) backedOff() time.Duration {
+	b := cb.backoff
+	if b == nil || cb.reopens == 0 {
+		return cb.recoveryTime
+	}
+
+	d := float64(cb.recoveryTime) * math.Pow(b.factor, float64(cb.reopens))
+	if b.max > 0 && d > float64(b.max) {
+		return max(b.max, cb.recoveryTime)
+	}
+	if d >= math.MaxInt64 {
+		return math.MaxInt64
+	}
+	return time.Duration(d)
+}
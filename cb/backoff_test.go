@@ -0,0 +1,63 @@
+package cb
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestCircuitBreaker_Backoff(t *testing.T) {
+	t.Parallel()
+
+	clock := newFakeClock()
+	cb := NewCircuitBreaker(1, time.Second, 1, time.Second, WithClock(clock), WithBackoff(2, 5*time.Second))
+
+	failFn := func() (any, error) {
+		return nil, errFailure
+	}
+	successFn := func() (any, error) {
+		return 42, nil
+	}
+
+	_, _ = cb.Call(failFn)
+
+	// Each failed probe round doubles the wait, up to the ceiling
+	for _, want := range []time.Duration{time.Second, 2 * time.Second, 4 * time.Second, 5 * time.Second, 5 * time.Second} {
+		if got := cb.recoveryWindow(); got != want {
+			t.Fatalf("expected a recovery window of %v, got %v", want, got)
+		}
+
+		clock.Advance(want)
+		_, _ = cb.Call(successFn)
+		if cb.state != Open {
+			t.Fatalf("expected the circuit to stay open until %v passed, got %s", want, cb.state)
+		}
+
+		clock.Advance(time.Millisecond)
+		_, _ = cb.Call(failFn) // Moves the circuit to half-open and probes
+		if cb.state != Open {
+			t.Fatalf("expected the failed probe to reopen the circuit, got %s", cb.state)
+		}
+	}
+
+	// Closing resets the backoff
+	clock.Advance(5*time.Second + time.Millisecond)
+	_, _ = cb.Call(successFn)
+	if cb.state != Closed {
+		t.Fatalf("expected state closed, got %s", cb.state)
+	}
+	_, _ = cb.Call(failFn)
+	if got := cb.recoveryWindow(); got != time.Second {
+		t.Fatalf("expected the recovery window to reset to 1s, got %v", got)
+	}
+}
+
+func TestCircuitBreaker_BackoffDescribe(t *testing.T) {
+	t.Parallel()
+
+	cb := NewCircuitBreaker(1, time.Second, 1, time.Second, WithBackoff(2, time.Minute))
+
+	if out := cb.Describe(); !strings.Contains(out, "recovery backoff: x2 up to 1m0s, now 1s") {
+		t.Fatalf("expected the backoff in the description, got:\n%s", out)
+	}
+}
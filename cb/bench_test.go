@@ -0,0 +1,128 @@
+package cb
+
+import (
+	"io"
+	"log/slog"
+	"math"
+	"runtime"
+	"testing"
+	"time"
+)
+
+// benchGoroutines is the number of goroutines the contended benchmarks run on
+const benchGoroutines = 1000
+
+// newBenchBreaker returns a breaker that only logs warnings and errors, to
+// nowhere, so benchmarks measure the breaker rather than the log output
+func newBenchBreaker(opts ...Option) *circuitBreaker {
+	logger := slog.New(slog.NewTextHandler(io.Discard, &slog.HandlerOptions{Level: slog.LevelWarn}))
+	return New(append([]Option{WithLogger(logger)}, opts...)...)
+}
+
+// runContended runs op from about benchGoroutines goroutines at once, passing
+// it the number of times the goroutine has run it so far
+func runContended(b *testing.B, op func(n int)) {
+	b.SetParallelism(max(benchGoroutines/runtime.GOMAXPROCS(0), 1))
+	b.ReportAllocs()
+	b.ResetTimer()
+	b.RunParallel(func(pb *testing.PB) {
+		for n := 0; pb.Next(); n++ {
+			op(n)
+		}
+	})
+}
+
+func BenchmarkCall_Closed(b *testing.B) {
+	for _, bc := range []struct {
+		name    string
+		timeout time.Duration
+	}{
+		{"no timeout", 0},
+		{"timeout", time.Second},
+	} {
+		b.Run(bc.name, func(b *testing.B) {
+			cb := newBenchBreaker(WithTimeout(bc.timeout))
+			fn := func() (any, error) {
+				return 42, nil
+			}
+
+			b.ReportAllocs()
+			for range b.N {
+				_, _ = cb.Call(fn)
+			}
+		})
+	}
+}
+
+func BenchmarkCall_Open(b *testing.B) {
+	cb := newBenchBreaker(WithRecoveryTime(time.Hour))
+	cb.Trip()
+	fn := func() (any, error) {
+		return 42, nil
+	}
+
+	b.ReportAllocs()
+	for range b.N {
+		_, _ = cb.Call(fn)
+	}
+}
+
+func BenchmarkCall_HalfOpen(b *testing.B) {
+	// Probes are never exhausted and never enough to close the circuit
+	cb := newBenchBreaker(WithHalfOpenMaxRequests(math.MaxInt))
+	cb.setState(HalfOpen)
+	fn := func() (any, error) {
+		return 42, nil
+	}
+
+	b.ReportAllocs()
+	for range b.N {
+		_, _ = cb.Call(fn)
+	}
+}
+
+func BenchmarkCall_OpenContended(b *testing.B) {
+	cb := newBenchBreaker(WithRecoveryTime(time.Hour))
+	cb.Trip()
+
+	fn := func() (any, error) {
+		return 42, nil
+	}
+	runContended(b, func(int) {
+		_, _ = cb.Call(fn)
+	})
+}
+
+// BenchmarkState_OpenContended compares reading the state under the lock, as
+// State used to, with the lock-free read it does now, while the open circuit
+// rejects calls
+func BenchmarkState_OpenContended(b *testing.B) {
+	reads := map[string]func(cb *circuitBreaker) State{
+		"mutex": func(cb *circuitBreaker) State {
+			cb.mu.Lock()
+			defer cb.mu.Unlock()
+			return cb.reportedState()
+		},
+		"atomic": (*circuitBreaker).State,
+	}
+
+	for _, name := range []string{"mutex", "atomic"} {
+		read := reads[name]
+		b.Run(name, func(b *testing.B) {
+			cb := newBenchBreaker(WithRecoveryTime(time.Hour))
+			cb.Trip()
+
+			fn := func() (any, error) {
+				return 42, nil
+			}
+			runContended(b, func(n int) {
+				// One call in a hundred is a rejection, the rest read the state
+				if n%100 == 0 {
+					_, _ = cb.Call(fn)
+					return
+				}
+				_ = read(cb)
+			})
+		})
+	}
+}
@@ -0,0 +1,35 @@
+package cb
+
+import (
+	"context"
+	"time"
+)
+
+// Budget shares a single deadline across calls to several breakers of a
+// registry, so that a request fanning out to many dependencies in sequence
+// can't exceed its overall latency budget
+type Budget struct {
+	registry *Registry       // Registry the breakers come from
+	ctx      context.Context // Carries the shared deadline
+}
+
+// WithSharedBudget returns a budget that allows total time across all calls
+// made through it, starting now. Call the returned cancel function once the
+// budget is no longer needed to release its resources.
+func (r *Registry) WithSharedBudget(ctx context.Context, total time.Duration) (*Budget, context.CancelFunc) {
+	ctx, cancel := context.WithTimeout(ctx, total)
+	return &Budget{registry: r, ctx: ctx}, cancel
+}
+
+// Call runs fn through the named breaker, timing out when either the
+// breaker's own timeout or the remaining budget runs out. Once the budget is
+// spent it returns the context's error without calling fn.
+func (b *Budget) Call(name string, fn func() (any, error), opts ...CallOption) (any, error) {
+	return b.registry.Get(name).CallContext(b.ctx, ignoreContext(fn), opts...)
+}
+
+// Remaining returns the time left in the budget
+func (b *Budget) Remaining() time.Duration {
+	deadline, _ := b.ctx.Deadline()
+	return max(time.Until(deadline), 0)
+}
@@ -0,0 +1,58 @@
+package cb
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestBudget_SequentialCalls(t *testing.T) {
+	t.Parallel()
+
+	r := NewRegistry(func() *circuitBreaker {
+		return NewCircuitBreaker(5, time.Second, 1, time.Second)
+	})
+
+	budget, cancel := r.WithSharedBudget(context.Background(), 100*time.Millisecond)
+	defer cancel()
+
+	var calls atomic.Int32
+	slowFn := func() (any, error) {
+		calls.Add(1)
+		time.Sleep(40 * time.Millisecond)
+		return 42, nil
+	}
+
+	// Two calls fit within the budget
+	for _, name := range []string{"users", "orders"} {
+		if _, err := budget.Call(name, slowFn); err != nil {
+			t.Fatalf("expected the %s call to fit in the budget, got %v", name, err)
+		}
+	}
+
+	// The third runs out of budget well before the breaker's own timeout
+	start := time.Now()
+	_, err := budget.Call("payments", slowFn)
+	if err == nil || err.Error() != "request timed out" {
+		t.Fatalf("expected the third call to time out, got %v", err)
+	}
+	if elapsed := time.Since(start); elapsed > 40*time.Millisecond {
+		t.Fatalf("expected the remaining budget to cut the call short, took %v", elapsed)
+	}
+
+	// The fourth doesn't run at all
+	_, err = budget.Call("inventory", slowFn)
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("expected context.DeadlineExceeded once the budget is spent, got %v", err)
+	}
+
+	if got := calls.Load(); got != 3 {
+		t.Fatalf("expected 3 calls to run, got %d", got)
+	}
+
+	if budget.Remaining() != 0 {
+		t.Fatalf("expected no budget remaining, got %v", budget.Remaining())
+	}
+}
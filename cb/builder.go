@@ -0,0 +1,95 @@
+package cb
+
+import (
+	"errors"
+	"fmt"
+	"time"
+)
+
+// Builder accumulates the settings of a breaker and validates them on Build,
+// turning settings that can't work, like a zero failure threshold that trips
+// on the first call, into an error at construction rather than a surprise at
+// runtime. The zero value is ready to use.
+type Builder struct {
+	opts []Option // Options applied in order by Build
+}
+
+// NewBuilder returns a builder starting from the defaults of New
+func NewBuilder() *Builder {
+	return &Builder{}
+}
+
+// Name sets the name that identifies the breaker
+func (b *Builder) Name(name string) *Builder {
+	return b.With(WithName(name))
+}
+
+// FailureThreshold sets the number of failures that trips the circuit
+func (b *Builder) FailureThreshold(n int) *Builder {
+	return b.With(WithFailureThreshold(n))
+}
+
+// RecoveryTime sets how long the open circuit waits before probing
+func (b *Builder) RecoveryTime(d time.Duration) *Builder {
+	return b.With(WithRecoveryTime(d))
+}
+
+// HalfOpenMaxRequests sets the number of probes the half-open circuit admits
+func (b *Builder) HalfOpenMaxRequests(n int) *Builder {
+	return b.With(WithHalfOpenMaxRequests(n))
+}
+
+// SuccessesToClose sets the number of successful probes that closes the
+// half-open circuit
+func (b *Builder) SuccessesToClose(n int) *Builder {
+	return b.With(WithSuccessesToClose(n))
+}
+
+// Timeout sets how long a request may run before it times out
+func (b *Builder) Timeout(d time.Duration) *Builder {
+	return b.With(WithTimeout(d))
+}
+
+// With adds options for the settings the builder has no method for. Their
+// result is validated like the rest.
+func (b *Builder) With(opts ...Option) *Builder {
+	b.opts = append(b.opts, opts...)
+	return b
+}
+
+// Build creates the breaker, or returns an error wrapping ErrInvalidConfig
+// for every setting that can't work
+func (b *Builder) Build() (*circuitBreaker, error) {
+	cb := New(b.opts...)
+	if err := cb.validate(); err != nil {
+		return nil, err
+	}
+	return cb, nil
+}
+
+// validate checks that the breaker's settings can work
+func (cb *circuitBreaker) validate() error {
+	var errs []error
+	check := func(ok bool, format string, args ...any) {
+		if !ok {
+			errs = append(errs, fmt.Errorf("%w: "+format, append([]any{ErrInvalidConfig}, args...)...))
+		}
+	}
+
+	check(cb.failureThreshold >= 1, "failure threshold must be at least 1, got %d", cb.failureThreshold)
+	check(cb.recoveryTime > 0, "recovery time must be positive, got %s", cb.recoveryTime)
+	check(cb.halfOpenMaxRequests >= 1, "half-open max requests must be at least 1, got %d", cb.halfOpenMaxRequests)
+	check(cb.successesToClose >= 0, "successes to close can't be negative, got %d", cb.successesToClose)
+	check(cb.successesToClose <= cb.halfOpenMaxRequests, "successes to close can't exceed the half-open max requests, got %d > %d", cb.successesToClose, cb.halfOpenMaxRequests)
+	check(cb.weightedThreshold >= 0, "weighted failure threshold can't be negative, got %g", cb.weightedThreshold)
+	check(cb.maxConcurrent >= 0, "max concurrent can't be negative, got %d", cb.maxConcurrent)
+	check(cb.probeInterval >= 0, "probe interval can't be negative, got %s", cb.probeInterval)
+	check(cb.probeTolerance >= 0, "half-open failure tolerance can't be negative, got %d", cb.probeTolerance)
+	check(cb.latencySize >= 1, "latency reservoir size must be at least 1, got %d", cb.latencySize)
+	check(!cb.noShedding, "gradual shedding needs a controller, e.g. LinearShedding with a positive max ratio")
+	if l := cb.limit; l != nil {
+		check(l.n >= 1, "rate limit must allow at least 1 request, got %d", l.n)
+		check(l.interval > 0, "rate limit interval must be positive, got %s", l.interval)
+	}
+	return errors.Join(errs...)
+}
@@ -0,0 +1,82 @@
+package cb
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestBuilder_Build(t *testing.T) {
+	t.Parallel()
+
+	cb, err := NewBuilder().
+		Name("db").
+		FailureThreshold(3).
+		RecoveryTime(time.Minute).
+		HalfOpenMaxRequests(4).
+		SuccessesToClose(2).
+		Timeout(time.Second).
+		Build()
+	if err != nil {
+		t.Fatalf("expected valid settings to build, got %v", err)
+	}
+
+	want := Config{Name: "db", FailureThreshold: 3, RecoveryTime: time.Minute, HalfOpenMaxRequests: 4, SuccessesToClose: 2, Timeout: time.Second}
+	if got := cb.Config(); got != want {
+		t.Fatalf("expected config %+v, got %+v", want, got)
+	}
+}
+
+func TestBuilder_Defaults(t *testing.T) {
+	t.Parallel()
+
+	var b Builder
+	if _, err := b.Build(); err != nil {
+		t.Fatalf("expected the defaults to build, got %v", err)
+	}
+}
+
+func TestBuilder_Validation(t *testing.T) {
+	t.Parallel()
+
+	tests := map[string]struct {
+		builder *Builder
+		want    string
+	}{
+		"zero failure threshold":     {NewBuilder().FailureThreshold(0), "failure threshold must be at least 1"},
+		"negative failure threshold": {NewBuilder().FailureThreshold(-1), "failure threshold must be at least 1"},
+		"zero recovery time":         {NewBuilder().RecoveryTime(0), "recovery time must be positive"},
+		"zero half-open max":         {NewBuilder().HalfOpenMaxRequests(0), "half-open max requests must be at least 1"},
+		"negative successes":         {NewBuilder().SuccessesToClose(-1), "successes to close can't be negative"},
+		"successes above max":        {NewBuilder().HalfOpenMaxRequests(2).SuccessesToClose(3), "successes to close can't exceed"},
+		"negative weighted":          {NewBuilder().With(WithWeightedFailureThreshold(-1)), "weighted failure threshold can't be negative"},
+		"negative max concurrent":    {NewBuilder().With(WithMaxConcurrent(-1)), "max concurrent can't be negative"},
+		"negative probe interval":    {NewBuilder().With(WithHalfOpenProbeInterval(-time.Second)), "probe interval can't be negative"},
+		"negative tolerance":         {NewBuilder().With(WithHalfOpenFailureTolerance(-1)), "half-open failure tolerance can't be negative"},
+		"zero reservoir":             {NewBuilder().With(WithLatencyReservoir(0)), "latency reservoir size must be at least 1"},
+		"negative reservoir":         {NewBuilder().With(WithLatencyReservoir(-1)), "latency reservoir size must be at least 1"},
+		"zero rate limit":            {NewBuilder().With(WithRequestRateLimit(0, time.Second)), "rate limit must allow at least 1 request"},
+		"zero rate limit interval":   {NewBuilder().With(WithRequestRateLimit(10, 0)), "rate limit interval must be positive"},
+		"zero shedding max ratio":    {NewBuilder().With(WithGradualShedding(LinearShedding(0))), "gradual shedding needs a controller"},
+	}
+
+	for name, tt := range tests {
+		cb, err := tt.builder.Build()
+		if !errors.Is(err, ErrInvalidConfig) || !strings.Contains(err.Error(), tt.want) {
+			t.Fatalf("%s: expected ErrInvalidConfig saying %q, got %v", name, tt.want, err)
+		}
+		if cb != nil {
+			t.Fatalf("%s: expected no breaker", name)
+		}
+	}
+}
+
+func TestBuilder_ReportsEveryProblem(t *testing.T) {
+	t.Parallel()
+
+	_, err := NewBuilder().FailureThreshold(0).RecoveryTime(0).Build()
+	if err == nil || !strings.Contains(err.Error(), "failure threshold") || !strings.Contains(err.Error(), "recovery time") {
+		t.Fatalf("expected both problems to be reported, got %v", err)
+	}
+}
@@ -0,0 +1,29 @@
+package cb
+
+// WithMaxConcurrent caps the number of calls in flight at once to n while the
+// circuit is closed, so that a slow dependency can't tie up every goroutine
+// even before it fails. Calls beyond the limit are rejected with
+// ErrConcurrencyLimit, an ErrTooManyRequests, and don't count as failures. A
+// call that times out keeps counting until its function returns, so a hung
+// dependency can't pile up goroutines behind a short timeout. Zero or
+// negative means no limit.
+func WithMaxConcurrent(n int) Option {
+	return func(cb *circuitBreaker) {
+		cb.maxConcurrent = n
+	}
+}
+
+// bulkheadFull reports whether the closed circuit already runs as many calls
+// as it allows
+func (cb *circuitBreaker) bulkheadFull() bool {
+	return cb.maxConcurrent > 0 && cb.inFlight >= cb.maxConcurrent
+}
+
+// release lets go of the caller's hold on the call's concurrency slot, which
+// is freed once the function running on its own goroutine, if any, has
+// returned too. It's called with the lock held.
+func (cb *circuitBreaker) release(c *call) {
+	if c.holders.Add(-1) == 0 {
+		cb.inFlight--
+	}
+}
@@ -0,0 +1,92 @@
+package cb
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestCircuitBreaker_MaxConcurrent(t *testing.T) {
+	t.Parallel()
+
+	cb := NewCircuitBreaker(1, time.Minute, 1, time.Second, WithMaxConcurrent(2))
+
+	started, release := make(chan struct{}), make(chan struct{})
+	done := make(chan error, 2)
+	for i := 0; i < 2; i++ {
+		go func() {
+			_, err := cb.Call(func() (any, error) {
+				started <- struct{}{}
+				<-release
+				return 42, nil
+			})
+			done <- err
+		}()
+	}
+	<-started
+	<-started
+
+	if got := cb.Stats().InFlight; got != 2 {
+		t.Fatalf("expected 2 calls in flight, got %d", got)
+	}
+
+	_, err := cb.Call(func() (any, error) {
+		return 42, nil
+	})
+	if !errors.Is(err, ErrConcurrencyLimit) || !errors.Is(err, ErrTooManyRequests) {
+		t.Fatalf("expected ErrConcurrencyLimit, got %v", err)
+	}
+	if cb.State() != Closed {
+		t.Fatalf("expected the rejection not to count as a failure, got %s", cb.State())
+	}
+
+	close(release)
+	for i := 0; i < 2; i++ {
+		if err := <-done; err != nil {
+			t.Fatalf("expected the running calls to succeed, got %v", err)
+		}
+	}
+
+	if got := cb.Stats().InFlight; got != 0 {
+		t.Fatalf("expected no calls in flight, got %d", got)
+	}
+	if _, err := cb.Call(func() (any, error) { return 42, nil }); err != nil {
+		t.Fatalf("expected a call to be admitted once the others finished, got %v", err)
+	}
+}
+
+func TestCircuitBreaker_MaxConcurrentHoldsTimedOutCalls(t *testing.T) {
+	t.Parallel()
+
+	cb := NewCircuitBreaker(100, time.Minute, 1, 5*time.Millisecond, WithMaxConcurrent(2))
+
+	hang := make(chan struct{})
+	hungFn := func() (any, error) {
+		<-hang
+		return 42, nil
+	}
+
+	for i := 0; i < 2; i++ {
+		if _, err := cb.Call(hungFn); !errors.Is(err, ErrTimeout) {
+			t.Fatalf("expected call %d to time out, got %v", i+1, err)
+		}
+	}
+
+	// The timed-out functions are still running, so they still hold the slots
+	for i := 0; i < 10; i++ {
+		if _, err := cb.Call(hungFn); !errors.Is(err, ErrConcurrencyLimit) {
+			t.Fatalf("expected ErrConcurrencyLimit while the hung calls run, got %v", err)
+		}
+	}
+	if got := cb.Stats().InFlight; got != 2 {
+		t.Fatalf("expected 2 calls in flight, got %d", got)
+	}
+
+	close(hang)
+	if !waitFor(t, func() bool { return cb.Stats().InFlight == 0 }) {
+		t.Fatalf("expected the slots to be freed once the functions returned")
+	}
+	if _, err := cb.Call(func() (any, error) { return 42, nil }); err != nil {
+		t.Fatalf("expected a call to be admitted once the slots were freed, got %v", err)
+	}
+}
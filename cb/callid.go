@@ -0,0 +1,41 @@
+package cb
+
+import (
+	"context"
+	"log/slog"
+)
+
+// callIDKey is the context key under which a call's correlation ID is stored
+type callIDKey struct{}
+
+// logAttrsKey is the context key under which a call's log attributes are
+// stored
+type logAttrsKey struct{}
+
+// ContextWithCallID returns a copy of ctx carrying the correlation ID id. Calls
+// made with the returned context attach it to their log lines under the
+// "callID" attribute.
+func ContextWithCallID(ctx context.Context, id string) context.Context {
+	return context.WithValue(ctx, callIDKey{}, id)
+}
+
+// CallIDFromContext returns the correlation ID stored in ctx, if any
+func CallIDFromContext(ctx context.Context) string {
+	id, _ := ctx.Value(callIDKey{}).(string)
+	return id
+}
+
+// WithLogAttrs returns a copy of ctx carrying attrs on top of any it already
+// carries. Calls made with the returned context attach them to every log line
+// they emit, so a request's trip through the breaker can be traced with
+// whatever the rest of the pipeline logs it with.
+func WithLogAttrs(ctx context.Context, attrs ...slog.Attr) context.Context {
+	prev := logAttrsFromContext(ctx)
+	return context.WithValue(ctx, logAttrsKey{}, append(prev[:len(prev):len(prev)], attrs...))
+}
+
+// logAttrsFromContext returns the log attributes stored in ctx, if any
+func logAttrsFromContext(ctx context.Context) []slog.Attr {
+	attrs, _ := ctx.Value(logAttrsKey{}).([]slog.Attr)
+	return attrs
+}
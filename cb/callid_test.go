@@ -0,0 +1,139 @@
+package cb
+
+import (
+	"context"
+	"log/slog"
+	"sync"
+	"testing"
+	"time"
+)
+
+// recordingHandler is a slog.Handler that keeps every record it handles
+type recordingHandler struct {
+	mu      sync.Mutex
+	attrs   []slog.Attr
+	records *[]slog.Record
+}
+
+func newRecordingHandler() *recordingHandler {
+	return &recordingHandler{records: &[]slog.Record{}}
+}
+
+func (h *recordingHandler) Enabled(context.Context, slog.Level) bool {
+	return true
+}
+
+func (h *recordingHandler) Handle(_ context.Context, r slog.Record) error {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	r = r.Clone()
+	r.AddAttrs(h.attrs...)
+	*h.records = append(*h.records, r)
+	return nil
+}
+
+func (h *recordingHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	return &recordingHandler{attrs: append(h.attrs[:len(h.attrs):len(h.attrs)], attrs...), records: h.records}
+}
+
+func (h *recordingHandler) WithGroup(string) slog.Handler {
+	return h
+}
+
+// callIDs returns the callID attribute of every record, empty for none
+func (h *recordingHandler) callIDs() []string {
+	return h.attrValues("callID")
+}
+
+// attrValues returns the key attribute of every record, empty for none
+func (h *recordingHandler) attrValues(key string) []string {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	var values []string
+	for _, r := range *h.records {
+		value := ""
+		r.Attrs(func(a slog.Attr) bool {
+			if a.Key == key {
+				value = a.Value.String()
+			}
+			return true
+		})
+		values = append(values, value)
+	}
+	return values
+}
+
+// Swaps the default logger, so it can't run in parallel
+func TestCircuitBreaker_CallIDInLogs(t *testing.T) {
+	h := newRecordingHandler()
+	defer slog.SetDefault(slog.Default())
+	slog.SetDefault(slog.New(h))
+
+	cb := NewCircuitBreaker(1, time.Minute, 1, 2*time.Second)
+	r := NewRegistry(func() *circuitBreaker {
+		return NewCircuitBreaker(1, time.Minute, 1, 2*time.Second)
+	})
+
+	failFn := func() (any, error) {
+		return nil, errFailure
+	}
+
+	// Trips the circuit, then gets rejected
+	_, _ = cb.Call(failFn, WithCallID("req-1"))
+	_, _ = cb.Call(failFn, WithCallID("req-2"))
+
+	// Picked up from the context
+	budget, cancel := r.WithSharedBudget(ContextWithCallID(context.Background(), "req-3"), time.Second)
+	defer cancel()
+	_, _ = budget.Call("dep", failFn)
+
+	ids := h.callIDs()
+	if len(ids) == 0 {
+		t.Fatalf("expected log records")
+	}
+
+	seen := map[string]bool{}
+	for _, id := range ids {
+		if id == "" {
+			t.Fatalf("expected every call log to carry a callID, got %v", ids)
+		}
+		seen[id] = true
+	}
+
+	for _, want := range []string{"req-1", "req-2", "req-3"} {
+		if !seen[want] {
+			t.Fatalf("expected logs tagged with %s, got %v", want, ids)
+		}
+	}
+}
+
+func TestCircuitBreaker_LogAttrsFromContext(t *testing.T) {
+	t.Parallel()
+
+	h := newRecordingHandler()
+	cb := NewCircuitBreaker(1, time.Minute, 1, 2*time.Second, WithLogger(slog.New(h)))
+
+	ctx := WithLogAttrs(context.Background(), slog.String("requestID", "abc"))
+	ctx = WithLogAttrs(ctx, slog.String("route", "/users"))
+	_, _ = cb.CallContext(ctx, func(context.Context) (any, error) {
+		return nil, errFailure
+	})
+
+	for _, key := range []string{"requestID", "route"} {
+		values := h.attrValues(key)
+		if len(values) == 0 {
+			t.Fatalf("expected log records")
+		}
+		for _, v := range values {
+			if v == "" {
+				t.Fatalf("expected every log line to carry %s, got %v", key, values)
+			}
+		}
+	}
+
+	if got := logAttrsFromContext(context.Background()); len(got) != 0 {
+		t.Fatalf("expected no attributes in a bare context, got %v", got)
+	}
+}
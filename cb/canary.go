@@ -0,0 +1,56 @@
+package cb
+
+// canarySuccessRate is the share of canary probes that must succeed for the
+// circuit to close
+const canarySuccessRate = 0.5
+
+// WithCanaryRecovery makes the half-open circuit send only fraction of its
+// calls to the dependency as canary probes, rejecting the rest as if it were
+// still open. Probe failures don't reopen the circuit right away. Once the
+// half-open max requests probes have completed, the circuit closes if at
+// least half of them succeeded and reopens otherwise.
+func WithCanaryRecovery(fraction float64) Option {
+	return func(cb *circuitBreaker) {
+		cb.canaryFraction = fraction
+	}
+}
+
+// isCanary reports whether the current half-open call gets to probe
+func (cb *circuitBreaker) isCanary() bool {
+	return cb.canaryFraction <= 0 || cb.rand.Float64() < cb.canaryFraction
+}
+
+// finishCanary records a canary probe's outcome and, once every probe of the
+// episode is in, closes or reopens the circuit based on their success rate
+func (cb *circuitBreaker) finishCanary(c *call, result any, err error) (any, error) {
+	if cls := cb.classify(c, err); cls.IsFailure {
+		c.log.Warn("Canary probe failed in half-open state", "reason", c.failureReason())
+		cb.tallyFailure(c)
+		cb.counts.onFailure()
+		result = nil
+	} else {
+		cb.recordSuccess(c)
+		cb.logCall(c, "Canary probe succeeded in half-open state")
+	}
+
+	// Decide once every admitted probe is in, not just the last one admitted
+	done := cb.counts.TotalSuccesses + cb.counts.TotalFailures
+	if done < cb.halfOpenMaxRequests {
+		return result, err
+	}
+
+	rate := float64(cb.counts.TotalSuccesses) / float64(done)
+	if rate >= canarySuccessRate {
+		c.log.Info("Canary probes healthy, transitioning to closed", "successRate", rate)
+		if !cb.resetCircuit() {
+			cb.counts.clear()
+		}
+		return result, err
+	}
+
+	c.log.Error("Canary probes unhealthy, transitioning to open", "successRate", rate)
+	if cb.setState(Open) {
+		cb.startRecovery()
+	}
+	return result, err
+}
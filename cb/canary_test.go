@@ -0,0 +1,70 @@
+package cb
+
+import (
+	"errors"
+	"math/rand"
+	"testing"
+	"time"
+)
+
+func TestCircuitBreaker_CanaryFraction(t *testing.T) {
+	t.Parallel()
+
+	cb := NewCircuitBreaker(
+		1, time.Second, 10_000, 2*time.Second,
+		WithRandSource(rand.NewSource(3)),
+		WithCanaryRecovery(0.2),
+	)
+	cb.setState(HalfOpen)
+
+	probes := 0
+	for i := 0; i < 1000; i++ {
+		_, err := cb.Call(func() (any, error) {
+			probes++
+			return 42, nil
+		})
+		if err != nil && !errors.Is(err, ErrCircuitOpen) {
+			t.Fatalf("expected non-canaries to be rejected as open, got %v", err)
+		}
+	}
+
+	if probes < 150 || probes > 250 {
+		t.Fatalf("expected roughly 200 of 1000 calls to probe, got %d", probes)
+	}
+
+	if cb.state != HalfOpen {
+		t.Fatalf("expected state half-open until all probes are in, got %s", cb.state)
+	}
+}
+
+func TestCircuitBreaker_CanaryDecision(t *testing.T) {
+	t.Parallel()
+
+	// runProbes feeds results, true for success, to a half-open canary breaker
+	// until every probe has run
+	runProbes := func(results ...bool) *circuitBreaker {
+		cb := NewCircuitBreaker(1, time.Second, len(results), 2*time.Second, WithCanaryRecovery(0.5))
+		cb.setState(HalfOpen)
+
+		for i := 0; i < len(results); {
+			_, _ = cb.Call(func() (any, error) {
+				ok := results[i]
+				i++
+				if ok {
+					return 42, nil
+				}
+				return nil, errFailure
+			})
+		}
+		return cb
+	}
+
+	// A failed probe doesn't reopen the circuit on its own
+	if cb := runProbes(false, true, true, false); cb.state != Closed {
+		t.Fatalf("expected half the probes succeeding to close, got %s", cb.state)
+	}
+
+	if cb := runProbes(true, false, false, false); cb.state != Open {
+		t.Fatalf("expected mostly failing probes to reopen, got %s", cb.state)
+	}
+}
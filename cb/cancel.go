@@ -0,0 +1,57 @@
+package cb
+
+import (
+	"context"
+	"errors"
+)
+
+// WithCountCanceled counts calls the caller cancelled as failures, a call cut
+// short by the cancellation being a timeout. By default they count neither
+// way and return the context's error, since a caller giving up says nothing
+// about the dependency.
+func WithCountCanceled() Option {
+	return func(cb *circuitBreaker) {
+		cb.countCanceled = true
+	}
+}
+
+// WithIgnoreCallerDeadline treats calls cut short by the deadline of the
+// caller's context like cancelled ones, rather than as timeouts. Only the
+// caller's own deadline is ignored: the breaker's timeout still counts, and so
+// does a context.DeadlineExceeded the function returns while the caller's
+// context is still live.
+func WithIgnoreCallerDeadline() Option {
+	return func(cb *circuitBreaker) {
+		cb.ignoreDeadline = true
+	}
+}
+
+// callerGaveUp reports whether the call ended because its caller cancelled
+// it or, if so configured, because the caller's deadline passed
+func (cb *circuitBreaker) callerGaveUp(c *call, err error) bool {
+	switch {
+	case errors.Is(err, context.Canceled):
+		return !cb.countCanceled
+	case errors.Is(err, context.DeadlineExceeded):
+		return cb.ignoreDeadline && errors.Is(c.ctx.Err(), context.DeadlineExceeded)
+	}
+	return false
+}
+
+// finishCanceled settles a call its caller gave up on without counting it
+// toward any decision. A half-open probe gives its slot back, so the probe
+// budget isn't spent on callers that left.
+func (cb *circuitBreaker) finishCanceled(c *call, err error) (any, error) {
+	cb.logCall(c, "Caller gave up on the request, not counting it")
+	c.outcome = OutcomeCanceled
+	cb.totals.canceled++
+	cb.emitCall(OutcomeCanceled)
+
+	if cb.generation == c.generation {
+		cb.probing = false
+		if c.state == HalfOpen {
+			cb.counts.Requests--
+		}
+	}
+	return nil, err
+}
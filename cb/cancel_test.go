@@ -0,0 +1,108 @@
+package cb
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestCircuitBreaker_CallerCancellationDoesNotTrip(t *testing.T) {
+	t.Parallel()
+
+	cb := NewCircuitBreaker(1, time.Minute, 1, time.Second)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	_, err := cb.CallContext(ctx, func(ctx context.Context) (any, error) {
+		cancel()
+		<-ctx.Done()
+		time.Sleep(10 * time.Millisecond)
+		return nil, ctx.Err()
+	})
+	if !errors.Is(err, context.Canceled) {
+		t.Fatalf("expected context.Canceled, got %v", err)
+	}
+
+	// A function that returns the cancellation itself doesn't count either
+	_, _ = cb.CallContext(context.Background(), func(context.Context) (any, error) {
+		return nil, context.Canceled
+	})
+
+	if cb.State() != Closed {
+		t.Fatalf("expected cancellations not to trip the circuit, got %s", cb.State())
+	}
+	if got := cb.Stats(); got.Canceled != 2 || got.Failures != 0 || got.Timeouts != 0 {
+		t.Fatalf("expected 2 cancellations and no failures, got %+v", got)
+	}
+}
+
+func TestCircuitBreaker_CancelledProbeGivesBackItsSlot(t *testing.T) {
+	t.Parallel()
+
+	cb := NewCircuitBreaker(1, time.Minute, 1, time.Second)
+	cb.setState(HalfOpen)
+
+	_, _ = cb.Call(func() (any, error) {
+		return nil, context.Canceled
+	})
+	if cb.State() != HalfOpen {
+		t.Fatalf("expected a cancelled probe to leave the circuit half-open, got %s", cb.State())
+	}
+	if _, err := cb.Call(func() (any, error) { return 42, nil }); err != nil {
+		t.Fatalf("expected another probe to be admitted, got %v", err)
+	}
+	if cb.State() != Closed {
+		t.Fatalf("expected state closed, got %s", cb.State())
+	}
+}
+
+func TestCircuitBreaker_CountCanceled(t *testing.T) {
+	t.Parallel()
+
+	cb := NewCircuitBreaker(1, time.Minute, 1, time.Second, WithCountCanceled())
+
+	_, _ = cb.Call(func() (any, error) {
+		return nil, context.Canceled
+	})
+	if cb.State() != Open {
+		t.Fatalf("expected a counted cancellation to trip the circuit, got %s", cb.State())
+	}
+}
+
+func TestCircuitBreaker_IgnoreCallerDeadline(t *testing.T) {
+	t.Parallel()
+
+	slowFn := func(ctx context.Context) (any, error) {
+		<-ctx.Done()
+		return nil, ctx.Err()
+	}
+
+	counted := NewCircuitBreaker(1, time.Minute, 1, time.Second)
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	if _, err := counted.CallContext(ctx, slowFn); !errors.Is(err, ErrTimeout) {
+		t.Fatalf("expected the caller's deadline to time the call out by default, got %v", err)
+	}
+	if counted.State() != Open {
+		t.Fatalf("expected the timeout to trip the circuit, got %s", counted.State())
+	}
+
+	ignored := NewCircuitBreaker(1, time.Minute, 1, time.Second, WithIgnoreCallerDeadline())
+	ctx, cancel = context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	if _, err := ignored.CallContext(ctx, slowFn); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("expected context.DeadlineExceeded, got %v", err)
+	}
+	if ignored.State() != Closed {
+		t.Fatalf("expected the caller's deadline not to trip the circuit, got %s", ignored.State())
+	}
+
+	// The breaker's own timeout still counts
+	_, err := ignored.CallWithTimeout(10*time.Millisecond, func() (any, error) {
+		time.Sleep(50 * time.Millisecond)
+		return 42, nil
+	})
+	if !errors.Is(err, ErrTimeout) || ignored.State() != Open {
+		t.Fatalf("expected the breaker's timeout to trip the circuit, got %v and %s", err, ignored.State())
+	}
+}
@@ -2,151 +2,1039 @@ package cb
 
 import (
 	"context"
-	"errors"
 	"log/slog"
 	"sync"
+	"sync/atomic"
 	"time"
 )
 
-const (
-	Closed   = "closed"
-	Open     = "open"
-	HalfOpen = "half-open"
-)
+// Counts holds the request counters the breaker bases its decisions on. The
+// counters are cleared on every state transition.
+type Counts struct {
+	Requests             int `json:"requests"`              // Number of requests made in the current state
+	TotalSuccesses       int `json:"total_successes"`       // Number of successful requests in the current state
+	TotalFailures        int `json:"total_failures"`        // Number of failed requests in the current state
+	ConsecutiveSuccesses int `json:"consecutive_successes"` // Number of successes since the last failure
+	ConsecutiveFailures  int `json:"consecutive_failures"`  // Number of failures since the last success
+}
+
+// onRequest records that a request was admitted
+func (c *Counts) onRequest() {
+	c.Requests++
+}
+
+// onSuccess records a successful request
+func (c *Counts) onSuccess() {
+	c.TotalSuccesses++
+	c.ConsecutiveSuccesses++
+	c.ConsecutiveFailures = 0
+}
+
+// onFailure records a failed request
+func (c *Counts) onFailure() {
+	c.TotalFailures++
+	c.ConsecutiveFailures++
+	c.ConsecutiveSuccesses = 0
+}
+
+// clear zeroes all the counters
+func (c *Counts) clear() {
+	*c = Counts{}
+}
 
 // circuitBreaker manages the state and behavior of the circuit breaker
 type circuitBreaker struct {
-	mu                   sync.Mutex // Guards the circuit breaker state
-	state                string     // Current state of the circuit breaker
-	failureCount         int        // Number of consecutive failures
-	lastFailureTime      time.Time  // Time of the last failure
-	halfOpenSuccessCount int        // Number of successful requests in half-open state
+	mu                 sync.Mutex                           // Guards the circuit breaker state
+	state              State                                // Current state of the circuit breaker
+	published          atomic.Int32                         // Reported state, published for State to read without the lock
+	fastOpen           atomic.Pointer[openSnapshot]         // Set while open calls can be rejected without the lock, see publishOpen
+	fastRejections     atomic.Int64                         // Rejections made without the lock, yet to be added to the totals
+	counts             Counts                               // Request counters for the current state
+	failureScore       float64                              // Weighted score of the consecutive failures
+	openFor            time.Duration                        // Recovery time of the current open episode, zero for the default
+	jitter             time.Duration                        // Extra recovery delay drawn for the current open episode
+	totals             totals                               // Cumulative counters across all states
+	operations         map[string]*OpStats                  // Cumulative counters per named operation
+	hooks              []func()                             // Hooks to fire once the lock is released
+	listeners          map[int]func(change StateChange)     // Notified of every state transition, by subscription ID
+	nextListener       int                                  // ID of the next subscription
+	subscriptions      map[<-chan StateChange]*subscription // Channels handed out by Subscribe
+	awaitingFirstProbe bool                                 // Whether the first half-open probe is yet to finish
+	lastFailureTime    time.Time                            // Time of the last failure
+	openUntil          time.Time                            // When the open circuit is due to recover
+	lastFailureReason  FailureReason                        // Reason of the last failure, empty before any
+	lastActivity       time.Time                            // Time of the last call
+	created            time.Time                            // When the breaker was created
+	reopens            int                                  // Consecutive times the half-open circuit reopened or a two-state retry failed
+	disabled           bool                                 // Whether the breaker passes every call through untouched
+	generation         uint64                               // Incremented on every transition to tell stale outcomes apart
+	flights            flightGroup                          // Calls in flight through Do
+	probing            bool                                 // Whether a half-open probe is in flight
+	lastProbe          time.Time                            // When the current half-open episode last admitted a probe
+	halfOpenSince      time.Time                            // When the circuit last went half-open
+	inFlight           int                                  // Number of admitted calls yet to finish
+	prober             *prober                              // Background health checking, nil unless started
+	shutdown           chan struct{}                        // Closed by Shutdown to stop the breaker for good
 
 	failureThreshold    int           // Number of failures to trigger open state
 	recoveryTime        time.Duration // Time to wait before transitioning to half-open
 	halfOpenMaxRequests int           // Number of requests to allow in half-open state
-	timeout             time.Duration // Timeout for requests
+	successesToClose    int           // Successful probes that close the half-open circuit, see closeAfter
+	timeout             time.Duration // Timeout for requests, zero or negative for none
+
+	name    string       // Name used to identify the breaker in metrics
+	clock   Clock        // Source of the current time
+	limiter *tokenBucket // Optional limiter for requests in closed state
+	limit   *rateLimit   // Arguments of WithRequestRateLimit, kept for Builder to validate
+	rand    *lockedRand  // Shared source for all randomized behavior
+	sink    MetricsSink  // Receives metrics at each decision point
+	breaker string       // Name of the breaker, for the fallback's metrics
+	tracer  CallTracer   // Optional source of a span per call
+
+	latencies   *latencyReservoir  // Sample of observed call latencies
+	latencySize int                // Reservoir size asked for, kept for Builder to validate
+	shedding    SheddingController // Optional gradual load shedding in closed state
+	noShedding  bool               // Whether shedding was asked for without a controller, kept for Builder to validate
+	tightening  *tightening        // Optional timeout tightening for fast dependencies
+
+	preferResult      bool                                // Whether a result ready at the deadline beats the timeout
+	isFailure         func(error) bool                    // Decides whether an error counts as a failure
+	transitionGuard   func(from, to State) bool           // Optional veto over state transitions
+	failureWeight     func(error) float64                 // Optional weight of each failure toward the threshold
+	weightedThreshold float64                             // Failure score that trips a weighted circuit, zero for the failure threshold
+	classifier        func(error) Classification          // Optional rich classification of errors
+	retryableRecovery time.Duration                       // Recovery time after a retryable half-open failure, zero to ignore retryability
+	onFirstProbe      func(result any, err error)         // Observes the first probe of each recovery
+	idleReset         time.Duration                       // Idle period after which the breaker resets, zero to never
+	onCallComplete    func(time.Duration, Outcome, error) // Observes every call once it ends
+	onReject          func()                              // Observes every request blocked by the open circuit
+	retryBudget       *retryBudget                        // Optional cap on retries
+	canaryFraction    float64                             // Fraction of half-open calls used as canary probes, zero for all
+	onStateChange     func(from, to State)                // Optional callback fired after every transition
+	logger            *slog.Logger                        // Destination of the breaker's logs, slog.Default() when nil
+	verbose           bool                                // Whether per-call info logs are written
+	window            *failureWindow                      // Optional time window failures are counted over
+	rate              *failureRate                        // Optional failure rate the circuit trips on
+	slowRate          *failureRate                        // Optional ring of recent successes, true for a slow one
+	slowCall          time.Duration                       // Duration past which a successful call is slow
+	fallback          func(*call, error) (any, error)     // Optional source of results for rejected calls
+	fallbackOnFailure bool                                // Whether failed calls are handed to the fallback too
+	maxFallbackAge    time.Duration                       // Age past which cached fallback results are refused, zero for no limit
+	backoff           *backoff                            // Optional stretching of the recovery time of a flapping dependency
+	timeoutWeight     float64                             // Weight of a timeout toward the threshold, zero to weigh it like other failures
+	store             Store                               // Optional persistence of the state across restarts
+	maxConcurrent     int                                 // Cap on calls running at once in closed state, zero for none
+	customTrip        func(Counts) bool                   // Optional trip decision replacing the built-in ones
+	recoveryJitter    time.Duration                       // Upper bound of the extra recovery delay, zero for none
+	healthCheck       func() error                        // Optional check of the dependency run by StartProbing
+	warmup            time.Duration                       // Period after creation during which the circuit can't trip
+	singleProbe       bool                                // Whether the half-open circuit admits one probe at a time
+	probeInterval     time.Duration                       // Minimum spacing of half-open probes, zero for none
+	shouldProbe       func() bool                         // Optional decision on each half-open request that fits the budget
+	twoState          bool                                // Whether recovery closes the circuit without going half-open
+	probeTolerance    int                                 // Number of consecutive failed probes the half-open circuit absorbs
+	halfOpenWindow    time.Duration                       // Time the half-open circuit has to close before reopening, zero for no limit
+	countCanceled     bool                                // Whether calls the caller cancelled count as failures
+	ignoreDeadline    bool                                // Whether calls that outlived the caller's deadline don't count as failures
+}
+
+// Defaults of the settings New doesn't get an option for
+const (
+	defaultFailureThreshold    = 5
+	defaultRecoveryTime        = 30 * time.Second
+	defaultHalfOpenMaxRequests = 1
+)
+
+// New initializes a new circuit breaker configured by opts. Unless overridden,
+// it opens after 5 consecutive failures, waits 30 seconds before probing,
+// closes again after a single successful probe, and doesn't time requests
+// out:
+//
+//	breaker := cb.New(
+//		cb.WithFailureThreshold(3),
+//		cb.WithRecoveryTime(5*time.Second),
+//		cb.WithHalfOpenMaxRequests(2),
+//		cb.WithTimeout(2*time.Second),
+//	)
+func New(opts ...Option) *circuitBreaker {
+	cb := &circuitBreaker{
+		state:               Closed,
+		failureThreshold:    defaultFailureThreshold,
+		recoveryTime:        defaultRecoveryTime,
+		halfOpenMaxRequests: defaultHalfOpenMaxRequests,
+		clock:               realClock{},
+		rand:                newTimeSeededRand(),
+		latencies:           newLatencyReservoir(defaultLatencyReservoirSize),
+		latencySize:         defaultLatencyReservoirSize,
+		isFailure:           isAnyError,
+		sink:                noopSink{},
+		shutdown:            make(chan struct{}),
+	}
+
+	for _, opt := range opts {
+		opt(cb)
+	}
+	cb.created = cb.clock.Now()
+	cb.restore()
+	cb.publishState()
+	return cb
 }
 
-// NewCircuitBreaker initializes a new CircuitBreaker
+// NewCircuitBreaker initializes a new circuit breaker from positional
+// settings, like New with the matching options applied before opts. Prefer
+// New, whose named options can't be passed in the wrong order.
 func NewCircuitBreaker(
 	failureThreshold int,
 	recoveryTime time.Duration,
 	halfOpenMaxRequests int,
 	timeout time.Duration,
+	opts ...Option,
 ) *circuitBreaker {
-	return &circuitBreaker{
-		state:               Closed,
-		failureThreshold:    failureThreshold,
-		recoveryTime:        recoveryTime,
-		halfOpenMaxRequests: halfOpenMaxRequests,
-		timeout:             timeout,
+	return New(append([]Option{
+		WithFailureThreshold(failureThreshold),
+		WithRecoveryTime(recoveryTime),
+		WithHalfOpenMaxRequests(halfOpenMaxRequests),
+		WithTimeout(timeout),
+	}, opts...)...)
+}
+
+// call carries the parameters of a single invocation through the handlers
+type call struct {
+	ctx             context.Context                    // Parent of the context the call's timeout derives from
+	fn              func(context.Context) (any, error) // Function being protected
+	op              string                             // Operation name the outcome is bucketed under
+	isFailure       func(error) bool                   // Decides whether an error counts as a failure
+	classified      bool                               // Whether isFailure was overridden for this call
+	retry           bool                               // Whether the call retries an earlier one
+	timeoutOverride time.Duration                      // Timeout replacing the breaker's for this call, if positive
+	outcome         Outcome                            // How the call ended
+	duration        time.Duration                      // How long the function ran
+	callID          string                             // Correlation ID attached to the call's logs
+	log             *slog.Logger                       // Logger for the call's events
+	admitted        bool                               // Whether the call was admitted and its function is to run
+	madeIn          State                              // State that admitted or rejected the call, Disabled if disabled
+	disabled        bool                               // Whether the call was admitted by a disabled breaker
+	generation      uint64                             // Breaker generation the call was admitted in
+	state           State                              // State the call was admitted in
+	timeout         time.Duration                      // Timeout the function runs under, none when zero or negative
+	clock           Clock                              // Clock the function's duration is measured with
+	preferResult    bool                               // Whether a result ready by the deadline beats the timeout
+	holders         atomic.Int32                       // Parties yet to let go of the call's concurrency slot, see release
+	sink            MetricsSink                        // Sink the fallback reports to, taken under the lock
+	breaker         string                             // Name of the breaker, for the fallback's metrics
+	maxFallbackAge  time.Duration                      // Age past which the fallback refuses cached results
+}
+
+// CallOption configures a single invocation of the circuit breaker
+type CallOption func(*call)
+
+// WithCallClassifier overrides the breaker's failure classifier for a single
+// call. Errors it rejects are still returned but count as successes.
+func WithCallClassifier(isFailure func(err error) bool) CallOption {
+	return func(c *call) {
+		c.isFailure = isFailure
+		c.classified = true
+	}
+}
+
+// WithCallID attaches a correlation ID to every log line of a single call
+// under the "callID" attribute, taking precedence over one in the context
+func WithCallID(id string) CallOption {
+	return func(c *call) {
+		c.callID = id
+	}
+}
+
+// WithCallTimeout overrides the breaker's timeout for a single call, so that
+// one breaker can guard both fast and slow operations of a dependency. A zero
+// or negative timeout keeps the breaker's.
+func WithCallTimeout(timeout time.Duration) CallOption {
+	return func(c *call) {
+		c.timeoutOverride = timeout
+	}
+}
+
+// Call attempts to execute the provided function, managing state transitions.
+//
+// A call that times out returns right away, but Go can't stop fn: it keeps
+// running on its own goroutine, holding whatever it holds, until it returns.
+// A hung fn leaks that goroutine for good. Use CallContext with a function
+// that honors its context to have it cancelled on timeout instead.
+func (cb *circuitBreaker) Call(fn func() (any, error), opts ...CallOption) (any, error) {
+	return cb.CallContext(context.Background(), ignoreContext(fn), opts...)
+}
+
+// CallContext executes fn like Call, deriving the timeout context passed to fn
+// from ctx so that cancelling ctx also cancels the wait. If ctx is already done
+// it returns the context's error without calling fn. The context is cancelled
+// as soon as the call times out, so a fn that returns once its context is done
+// doesn't outlive the call.
+func (cb *circuitBreaker) CallContext(ctx context.Context, fn func(context.Context) (any, error), opts ...CallOption) (any, error) {
+	return cb.call(&call{ctx: ctx, fn: fn}, opts)
+}
+
+// CallWithTimeout executes fn like Call, but with timeout in place of the
+// breaker's own. A zero timeout falls back to the breaker's.
+func (cb *circuitBreaker) CallWithTimeout(timeout time.Duration, fn func() (any, error), opts ...CallOption) (any, error) {
+	return cb.Call(fn, append(opts, WithCallTimeout(timeout))...)
+}
+
+// CallNamed executes fn like Call, additionally bucketing its outcome under op
+// in Stats().ByOperation. The trip decision is shared by all operations.
+//
+// Each distinct op is tracked separately, so op should come from a small fixed
+// set such as method or endpoint names, never from request data. Once
+// maxOperations names are tracked, any new ones are bucketed under
+// OtherOperation.
+func (cb *circuitBreaker) CallNamed(op string, fn func() (any, error), opts ...CallOption) (any, error) {
+	return cb.call(&call{ctx: context.Background(), fn: ignoreContext(fn), op: op}, opts)
+}
+
+// CallWithState executes fn like Call, also returning the state the call was
+// made in: the state that admitted or rejected it, or Disabled. Unlike reading
+// State around the call, it can't be thrown off by concurrent calls, and a
+// call that ends the recovery period reports HalfOpen, being its first probe.
+func (cb *circuitBreaker) CallWithState(fn func() (any, error), opts ...CallOption) (any, State, error) {
+	c := &call{ctx: context.Background(), fn: ignoreContext(fn)}
+	result, err := cb.call(c, opts)
+	return result, c.madeIn, err
+}
+
+// ignoreContext adapts a function that doesn't take a context to one that does
+func ignoreContext(fn func() (any, error)) func(context.Context) (any, error) {
+	return func(context.Context) (any, error) {
+		return fn()
 	}
 }
 
-// Call attempts to execute the provided function, managing state transitions
-func (cb *circuitBreaker) Call(fn func() (any, error)) (any, error) {
+// call decides on the invocation under the lock, releases it while an
+// admitted function runs, then takes it again to record the outcome. Hooks
+// queued along the way fire whenever the lock is released, so they're free to
+// call back into the breaker.
+func (cb *circuitBreaker) call(c *call, opts []CallOption) (any, error) {
+	c.outcome = OutcomeRejected
+	for _, opt := range opts {
+		opt(c)
+	}
+	if err := c.ctx.Err(); err != nil {
+		c.madeIn = cb.State()
+		c.outcome = OutcomeCanceled
+		cb.mu.Lock()
+		if hook := cb.onCallComplete; hook != nil {
+			cb.afterUnlock(func() { hook(0, OutcomeCanceled, err) })
+		}
+		cb.unlock()
+		return nil, err
+	}
+
+	if c.callID == "" {
+		c.callID = CallIDFromContext(c.ctx)
+	}
+	var logArgs []any
+	for _, attr := range logAttrsFromContext(c.ctx) {
+		logArgs = append(logArgs, attr)
+	}
+	if c.callID != "" {
+		logArgs = append(logArgs, "callID", c.callID)
+	}
+	if err := cb.rejectFast(c, logArgs); err != nil {
+		return nil, err
+	}
+
 	cb.mu.Lock()
-	defer cb.mu.Unlock()
+	// Reconfigure may swap these while calls are running, so they're only
+	// read under the lock
+	if !c.classified {
+		c.isFailure = cb.isFailure
+	}
+	c.log = cb.log()
+	if len(logArgs) > 0 {
+		c.log = c.log.With(logArgs...)
+	}
+	tracer, name := cb.tracer, cb.name
+	var span CallSpan
+
+	generation := cb.generation
+	result, err := cb.dispatch(c)
+	tripped := cb.generation != generation && cb.state == Open
+	c.madeIn = cb.reportedState()
+	if c.admitted && !c.disabled {
+		c.madeIn = c.state
+	}
+	if c.admitted {
+		cb.unlock()
+		span = startSpan(c, tracer, name)
+		res, ok := cb.execute(c)
+		cb.mu.Lock()
+		generation = cb.generation
+		result, err = cb.finish(c, res, ok)
+		tripped = tripped || cb.generation != generation && cb.state == Open
+		if ok && res.err != nil && !c.disabled {
+			err = &CallError{State: c.state, Err: err}
+		}
+	}
+	if hook := cb.onCallComplete; hook != nil {
+		d, outcome := c.duration, c.outcome
+		cb.afterUnlock(func() { hook(d, outcome, err) })
+	}
+	fallback := cb.fallbackFor(c, err)
+	cb.unlock()
+	if span == nil {
+		span = startSpan(c, tracer, name)
+	}
+	endSpan(span, c, tripped, err)
+
+	if fallback != nil {
+		return fallback(c, err)
+	}
+	return result, err
+}
+
+// afterUnlock queues hook to run once the lock is released
+func (cb *circuitBreaker) afterUnlock(hook func()) {
+	cb.hooks = append(cb.hooks, hook)
+}
+
+// unlock releases the lock, then fires the hooks queued while it was held
+func (cb *circuitBreaker) unlock() {
+	hooks := cb.hooks
+	cb.hooks = nil
+	cb.mu.Unlock()
+
+	for _, hook := range hooks {
+		hook()
+	}
+}
+
+// log returns the logger the breaker writes to
+func (cb *circuitBreaker) log() *slog.Logger {
+	if cb.logger == nil {
+		return slog.Default()
+	}
+	return cb.logger
+}
+
+// logCall writes an info log about a single call, which only a verbose
+// breaker does, so that the hot path doesn't flood the logs
+func (cb *circuitBreaker) logCall(c *call, msg string, args ...any) {
+	if cb.verbose {
+		c.log.Info(msg, args...)
+	}
+}
+
+// dispatch hands the invocation to the handler of the current state, which
+// either admits it or settles it right away
+func (cb *circuitBreaker) dispatch(c *call) (any, error) {
+	if cb.isShutdown() {
+		c.log.Warn("Breaker shut down, rejecting request")
+		return nil, ErrShutdown
+	}
+
+	if cb.disabled {
+		cb.logCall(c, "Breaker disabled, passing the request through")
+		cb.admit(c)
+		c.disabled = true
+		return nil, nil
+	}
+
+	cb.resetIfIdle(c)
+
+	cb.logCall(c, "Making a request", "state", cb.state)
+	cb.totals.requests++
 
-	slog.Info("Making a request", "state", cb.state)
+	if !cb.admitRetry(c) {
+		c.log.Warn("Retry budget exhausted, rejecting retry")
+		cb.recordRejection(c)
+		return nil, ErrRetryBudgetExceeded
+	}
 
 	switch cb.state {
 	case Closed:
-		return cb.handleClosedState(fn)
+		return cb.handleClosedState(c)
 	case Open:
-		return cb.handleOpenState()
+		return cb.handleOpenState(c)
 	case HalfOpen:
-		return cb.handleHalfOpenState(fn)
+		return cb.handleHalfOpenState(c)
 	default:
-		return nil, errors.New("unknown circuit state")
+		// Only a corrupted breaker gets here, so heal it rather than reject
+		// every call from now on
+		c.log.Error("Unknown circuit state, resetting to closed", "state", int(cb.state))
+		cb.transition(Closed)
+		return cb.handleClosedState(c)
+	}
+}
+
+// State returns the current state of the circuit breaker, one of Closed, Open,
+// or HalfOpen, or Disabled while the breaker is disabled. It doesn't take the
+// lock, so health checks polling it don't queue up behind calls.
+func (cb *circuitBreaker) State() State {
+	return State(cb.published.Load())
+}
+
+// Counts returns a snapshot of the request counters for the current state
+func (cb *circuitBreaker) Counts() Counts {
+	cb.mu.Lock()
+	defer cb.mu.Unlock()
+
+	return cb.counts
+}
+
+// ResetHalfOpen discards the progress of the current half-open episode so that
+// recovery starts over, without leaving half-open. Probes still in flight
+// belong to the old episode, so they neither count toward the new one nor hold
+// on to its probe slots. It's a no-op in any other state.
+func (cb *circuitBreaker) ResetHalfOpen() {
+	cb.mu.Lock()
+	defer cb.mu.Unlock()
+
+	if cb.state != HalfOpen {
+		return
+	}
+
+	cb.generation++
+	cb.clearCounts()
+	cb.awaitingFirstProbe = true
+	cb.probing = false
+	cb.lastProbe = time.Time{}
+	cb.halfOpenSince = cb.clock.Now()
+	cb.log().Info("Half-open progress reset")
+}
+
+// Reconfigure applies opts to the running breaker, then re-evaluates its
+// state against the new configuration
+func (cb *circuitBreaker) Reconfigure(opts ...Option) {
+	cb.mu.Lock()
+	for _, opt := range opts {
+		opt(cb)
 	}
+	cb.publishState() // WithDisabled may have changed the reported state
+	cb.evaluate()
+	cb.unlock()
 }
 
-// handleClosedState executes the function and monitors failures
-func (cb *circuitBreaker) handleClosedState(fn func() (any, error)) (any, error) {
-	result, err := cb.runWithTimeout(fn)
-	if err != nil {
-		slog.Warn("Request failed in closed state", "failureCount", cb.failureCount+1)
-		cb.failureCount++
-		cb.lastFailureTime = time.Now()
+// Evaluate re-runs the transition decisions against the current counts and
+// transitions if they're warranted, without waiting for the next call
+func (cb *circuitBreaker) Evaluate() {
+	cb.mu.Lock()
+	cb.evaluate()
+	cb.unlock()
+}
 
-		if cb.failureCount >= cb.failureThreshold {
-			cb.state = Open
-			slog.Error("Failure threshold reached, transitioning to open")
+// evaluate trips a closed circuit or closes a half-open one if the counts
+// call for it
+func (cb *circuitBreaker) evaluate() {
+	switch cb.state {
+	case Closed:
+		if cb.readyToTrip(cb.counts) && cb.setState(Open) {
+			cb.startRecovery()
+			cb.log().Error("Failure threshold reached on evaluation, transitioning to open")
 		}
+	case HalfOpen:
+		if cb.counts.ConsecutiveSuccesses >= cb.closeAfter() && cb.resetCircuit() {
+			cb.log().Info("Max success in half-open reached on evaluation")
+		}
+	}
+}
+
+// handleClosedState admits the call unless a rate limit or load shedding
+// turns it away
+func (cb *circuitBreaker) handleClosedState(c *call) (any, error) {
+	if cb.limiter != nil && !cb.limiter.allow(cb.clock.Now()) {
+		c.log.Warn("Rate limit exceeded, rejecting request")
+		cb.totals.throttled++
+		cb.recordRejection(c)
+		return nil, ErrRateLimited
+	}
+
+	if cb.shed() {
+		c.log.Warn("Shedding load, rejecting request", "admissionFraction", cb.admissionFraction())
+		cb.recordRejection(c)
+		return nil, ErrLoadShed
+	}
+
+	if cb.bulkheadFull() {
+		c.log.Warn("Concurrency limit reached, rejecting request", "inFlight", cb.inFlight)
+		cb.recordRejection(c)
+		return nil, ErrConcurrencyLimit
+	}
+
+	cb.counts.onRequest()
+	cb.admit(c)
+	return nil, nil
+}
+
+// finishClosedState records the outcome of a call admitted in closed state
+// and monitors failures
+func (cb *circuitBreaker) finishClosedState(c *call, result any, err error) (any, error) {
+	switch cls := cb.classify(c, err); {
+	case cls.IsFailure && cls.Retryable:
+		c.log.Warn("Request failed in closed state with a retryable error, not counting it")
+		cb.tallyFailure(c)
+		return nil, err
+	case cls.IsFailure:
+		cb.recordFailure(c, cls.Weight)
 		return nil, err
 	}
 
-	slog.Info("Request succeeded in closed state")
-	cb.resetCircuit()
-	return result, nil
+	cb.recordSuccess(c)
+	cb.logCall(c, "Request succeeded in closed state")
+	cb.recordSlow(c)
+	return result, err
 }
 
-// handleOpenState blocks requests if recovery time hasn't passed
-func (cb *circuitBreaker) handleOpenState() (any, error) {
-	if time.Since(cb.lastFailureTime) > cb.recoveryTime {
-		cb.state = HalfOpen
-		cb.halfOpenSuccessCount = 0
-		cb.failureCount = 0
-		slog.Info("Recovery period over, transitioning to half-open")
-		return nil, nil
+// recordFailure counts a failure in closed state and trips the circuit once
+// the counts warrant it
+func (cb *circuitBreaker) recordFailure(c *call, weight float64) {
+	cb.tallyFailure(c)
+	cb.counts.onFailure()
+	cb.failureScore += weight
+	cb.lastFailureTime = cb.clock.Now()
+	if cb.window != nil {
+		cb.window.add(cb.lastFailureTime, weight)
+	}
+	if cb.rate != nil {
+		cb.rate.record(true)
+	}
+	c.log.Warn("Request failed in closed state", "failureCount", cb.counts.ConsecutiveFailures, "reason", c.failureReason())
+
+	if cb.readyToTrip(cb.counts) && cb.setState(Open) {
+		cb.startRecovery()
+		c.log.Error("Failure threshold reached, transitioning to open")
+	}
+}
+
+// tallyFailure adds a failure to the cumulative counters and metrics
+func (cb *circuitBreaker) tallyFailure(c *call) {
+	cb.totals.failures++
+	cb.opStats(c.op).Failures++
+	cb.emitCall(OutcomeFailure)
+	if c.outcome != OutcomeTimeout {
+		c.outcome = OutcomeFailure
+	}
+	cb.lastFailureReason = c.failureReason()
+	cb.emitFailure(cb.lastFailureReason)
+}
+
+// recordSuccess counts a successful request
+func (cb *circuitBreaker) recordSuccess(c *call) {
+	cb.counts.onSuccess()
+	cb.failureScore = 0
+	if cb.rate != nil && cb.state == Closed {
+		cb.rate.record(false)
+	}
+	cb.tallySuccess(c)
+}
+
+// tallySuccess adds a success to the cumulative counters and metrics
+func (cb *circuitBreaker) tallySuccess(c *call) {
+	cb.totals.successes++
+	cb.opStats(c.op).Successes++
+	cb.emitCall(OutcomeSuccess)
+	if c.outcome != OutcomeTimeout {
+		c.outcome = OutcomeSuccess
+	}
+}
+
+// recordRejection counts a request that was rejected without running
+func (cb *circuitBreaker) recordRejection(c *call) {
+	cb.totals.rejections++
+	cb.opStats(c.op).Rejections++
+	cb.emitCall(OutcomeRejected)
+	c.outcome = OutcomeRejected
+	cb.emitFailure(ReasonRejected)
+}
+
+// isAnyError is the default failure classifier, treating every error as a
+// failure
+func isAnyError(err error) bool {
+	return err != nil
+}
+
+// readyToTrip reports whether the counts warrant opening the circuit. With
+// failure weighting, the weighted score of the consecutive failures is
+// compared against the threshold instead of their number. With a failure
+// window, only the failures within it are considered, consecutive or not. A
+// failure rate takes precedence over both, and a custom trip decision over
+// everything. Nothing trips the circuit during the warmup.
+func (cb *circuitBreaker) readyToTrip(counts Counts) bool {
+	if cb.warmingUp() {
+		return false
+	}
+	if cb.tooSlow() {
+		return true
+	}
+	if cb.customTrip != nil {
+		return cb.customTrip(counts)
+	}
+	if cb.rate != nil {
+		return cb.rate.exceeded()
+	}
+	if cb.window != nil {
+		return cb.window.score(cb.clock.Now(), cb.weighted()) >= cb.tripScore()
+	}
+	if cb.weighted() {
+		return cb.failureScore >= cb.tripScore()
+	}
+	return counts.ConsecutiveFailures >= cb.threshold()
+}
+
+// handleOpenState blocks requests if recovery time hasn't passed. Once it has,
+// the circuit goes half-open and the request that noticed is its first probe.
+func (cb *circuitBreaker) handleOpenState(c *call) (any, error) {
+	if cb.clock.Now().After(cb.openUntil) {
+		if cb.twoState {
+			return cb.handleRetry(c)
+		}
+		if cb.setState(HalfOpen) {
+			c.log.Info("Recovery period over, transitioning to half-open")
+			return cb.handleHalfOpenState(c)
+		}
+
+		// A vetoed recovery waits out another full recovery period
+		cb.startRecovery()
+	}
+
+	retryAfter := max(cb.openUntil.Sub(cb.clock.Now()), 0)
+	c.log.Debug("Circuit is still open, blocking request", "retryAfter", retryAfter)
+	cb.rejectOpen(c)
+	return nil, &OpenError{retryAfter: retryAfter}
+}
+
+// rejectOpen counts a request blocked by the open circuit and queues the
+// OnReject hook
+func (cb *circuitBreaker) rejectOpen(c *call) {
+	cb.recordRejection(c)
+	if hook := cb.onReject; hook != nil {
+		cb.afterUnlock(hook)
+	}
+}
+
+// handleHalfOpenState admits the call as a probe while the probe budget
+// lasts, counting probes still in flight against it
+func (cb *circuitBreaker) handleHalfOpenState(c *call) (any, error) {
+	if cb.halfOpenExpired() {
+		c.log.Warn("Half-open window over, transitioning to open")
+		if cb.setState(Open) {
+			cb.startRecovery()
+			cb.rejectOpen(c)
+			return nil, &OpenError{retryAfter: cb.recoveryWindow()}
+		}
+	}
+
+	if !cb.isCanary() {
+		cb.logCall(c, "Request not picked as a canary, blocking request")
+		cb.recordRejection(c)
+		return nil, ErrCircuitOpen
+	}
+
+	if cb.probesUsed() >= cb.halfOpenMaxRequests {
+		c.log.Warn("Half-open probe budget exhausted, blocking request")
+		cb.recordRejection(c)
+		return nil, ErrHalfOpenBudgetExceeded
+	}
+
+	if cb.probeInFlight() {
+		c.log.Warn("Half-open probe in flight, blocking request")
+		cb.recordRejection(c)
+		return nil, ErrHalfOpenBudgetExceeded
 	}
 
-	slog.Warn("Circuit is still open, blocking request")
-	return nil, errors.New("circuit open, request blocked")
+	if cb.probeTooSoon() {
+		c.log.Warn("Half-open probe interval not over, blocking request")
+		cb.recordRejection(c)
+		return nil, ErrHalfOpenBudgetExceeded
+	}
+
+	if cb.shouldProbe != nil && !cb.shouldProbe() {
+		c.log.Warn("Request not picked as a probe, blocking request")
+		cb.recordRejection(c)
+		return nil, ErrHalfOpenBudgetExceeded
+	}
+
+	cb.counts.onRequest()
+	cb.probing = true
+	cb.lastProbe = cb.clock.Now()
+	cb.admit(c)
+	return nil, nil
 }
 
-// handleHalfOpenState executes the function and checks for recovery
-func (cb *circuitBreaker) handleHalfOpenState(fn func() (any, error)) (any, error) {
-	result, err := cb.runWithTimeout(fn)
-	if err != nil {
-		slog.Error("Request failed in half-open state, transitioning to open")
-		cb.state = Open
-		cb.lastFailureTime = time.Now()
+// finishHalfOpenState records the outcome of a probe and checks for recovery
+func (cb *circuitBreaker) finishHalfOpenState(c *call, result any, err error) (any, error) {
+	if cb.awaitingFirstProbe {
+		cb.awaitingFirstProbe = false
+		if hook := cb.onFirstProbe; hook != nil {
+			cb.afterUnlock(func() { hook(result, err) })
+		}
+	}
+
+	if cb.canaryFraction > 0 {
+		return cb.finishCanary(c, result, err)
+	}
+
+	if cb.halfOpenExpired() {
+		c.log.Warn("Probe finished after the half-open window, transitioning to open")
+		if cb.setState(Open) {
+			cb.startRecovery()
+		}
+		return cb.finishStale(c, result, err)
+	}
+
+	if cls := cb.classify(c, err); cls.IsFailure {
+		if cb.tolerateProbeFailure() {
+			c.log.Warn("Request failed in half-open state, tolerating it", "failureCount", cb.counts.ConsecutiveFailures, "reason", c.failureReason())
+			cb.tallyFailure(c)
+			return nil, err
+		}
+		c.log.Error("Request failed in half-open state, transitioning to open", "retryable", cls.Retryable, "reason", c.failureReason())
+		cb.tallyFailure(c)
+		if cb.setState(Open) {
+			if cls.Retryable {
+				cb.openFor = cb.retryableRecovery
+			}
+			cb.startRecovery()
+		}
 		return nil, err
 	}
 
-	cb.halfOpenSuccessCount++
-	slog.Info("Request succeeded in half-open state", "successCount", cb.halfOpenSuccessCount)
+	cb.recordSuccess(c)
+	cb.logCall(c, "Request succeeded in half-open state", "successCount", cb.counts.ConsecutiveSuccesses)
 
-	if cb.halfOpenSuccessCount >= cb.halfOpenMaxRequests {
-		slog.Info("Max success in half-open, transitioning to closed")
-		cb.resetCircuit()
+	if cb.counts.ConsecutiveSuccesses >= cb.closeAfter() {
+		c.log.Info("Max success in half-open, transitioning to closed")
+		if !cb.resetCircuit() {
+			// A vetoed close starts a fresh round of probes
+			cb.counts.clear()
+		}
 	}
 
-	return result, nil
+	return result, err
+}
+
+// closeAfter returns the number of consecutive successful probes that closes
+// the half-open circuit
+func (cb *circuitBreaker) closeAfter() int {
+	if cb.successesToClose > 0 && cb.successesToClose < cb.halfOpenMaxRequests {
+		return cb.successesToClose
+	}
+	return cb.halfOpenMaxRequests
+}
+
+// callResult is the outcome of running the protected function
+type callResult struct {
+	result any
+	err    error
+}
+
+// admit lets the call's function run once the lock is released, capturing
+// under the lock everything the run needs from the breaker
+func (cb *circuitBreaker) admit(c *call) {
+	c.admitted = true
+	cb.inFlight++
+	c.holders.Store(1)
+	c.state = cb.state
+	c.generation = cb.generation
+	c.timeout = cb.effectiveTimeout()
+	if c.timeoutOverride > 0 {
+		c.timeout = c.timeoutOverride
+	}
+	c.clock = cb.clock
+	c.preferResult = cb.preferResult
 }
 
-// runWithTimeout executes the provided function with a timeout
-func (cb *circuitBreaker) runWithTimeout(fn func() (any, error)) (any, error) {
-	ctx, cancel := context.WithTimeout(context.Background(), cb.timeout)
+// execute runs the admitted call's function without the lock, giving up on it
+// once the timeout passes. A zero or negative timeout lets the function run
+// for as long as it takes. Giving up cancels the function's context, which is
+// the only way to stop it, since the goroutine it runs on can't be killed.
+// Until it does stop, it keeps holding its concurrency slot.
+func (cb *circuitBreaker) execute(c *call) (callResult, bool) {
+	// With nothing to give up on, the function runs right here
+	if c.timeout <= 0 && c.ctx.Done() == nil {
+		start := c.clock.Now()
+		result, err := c.fn(c.ctx)
+		c.duration = c.clock.Now().Sub(start)
+		return callResult{result, err}, true
+	}
+
+	ctx, cancel := context.WithCancel(c.ctx)
+	if c.timeout > 0 {
+		ctx, cancel = context.WithTimeout(c.ctx, c.timeout)
+	}
 	defer cancel()
 
-	resultChan := make(chan struct {
-		result any
-		err    error
-	}, 1)
+	start := c.clock.Now()
+	resultChan := make(chan callResult, 1)
 
+	c.holders.Add(1)
 	go func() {
-		result, err := fn()
-		resultChan <- struct {
-			result any
-			err    error
-		}{result, err}
+		result, err := c.fn(ctx)
+		resultChan <- callResult{result, err}
+		if c.holders.Add(-1) == 0 {
+			cb.mu.Lock()
+			cb.inFlight--
+			cb.mu.Unlock()
+		}
 	}()
 
+	res, ok := awaitResult(ctx.Done(), resultChan, c.preferResult)
+	c.duration = c.clock.Now().Sub(start)
+	return res, ok
+}
+
+// finish records the outcome of an admitted call back under the lock. The
+// breaker may have changed state while the function ran, even if it's back in
+// the same state by now, in which case the outcome no longer says anything
+// about the current generation and only counts toward the totals.
+func (cb *circuitBreaker) finish(c *call, res callResult, ok bool) (any, error) {
+	cb.release(c)
+	if c.disabled {
+		return cb.finishDisabled(c, res, ok)
+	}
+
+	switch {
+	case !ok && cb.callerGaveUp(c, c.ctx.Err()):
+		// The caller gave up waiting, not the breaker
+		res = callResult{err: c.ctx.Err()}
+	case !ok:
+		c.outcome = OutcomeTimeout
+		c.log.Warn("Request timed out")
+		cb.totals.timeouts++
+		cb.sink.Incr(MetricTimeouts, map[string]string{"breaker": cb.name})
+		cb.observeTightening(c.duration, false)
+		res = callResult{err: ErrTimeout}
+	default:
+		cb.latencies.observe(c.duration, cb.rand)
+		if cb.emitting() {
+			cb.sink.Observe(MetricDuration, c.duration.Seconds(), map[string]string{"breaker": cb.name, "state": c.state.String()})
+		}
+		cb.observeTightening(c.duration, res.err == nil)
+	}
+
+	if cb.callerGaveUp(c, res.err) {
+		return cb.finishCanceled(c, res.err)
+	}
+	if cb.generation != c.generation {
+		return cb.finishStale(c, res.result, res.err)
+	}
+	switch c.state {
+	case HalfOpen:
+		cb.probing = false
+		return cb.finishHalfOpenState(c, res.result, res.err)
+	case Open:
+		return cb.finishRetry(c, res.result, res.err)
+	}
+	return cb.finishClosedState(c, res.result, res.err)
+}
+
+// finishStale records the outcome of a call admitted in a state the breaker
+// has since left, without letting it sway the current one
+func (cb *circuitBreaker) finishStale(c *call, result any, err error) (any, error) {
+	cb.logCall(c, "State changed while the request ran, not counting it", "admittedIn", c.state, "state", cb.state)
+	if cls := cb.classify(c, err); cls.IsFailure {
+		cb.tallyFailure(c)
+		return nil, err
+	}
+
+	cb.tallySuccess(c)
+	return result, err
+}
+
+// awaitResult waits for the function's result until done is closed, reporting
+// whether the result arrived in time. When both are ready at once, select
+// picks one at random unless preferResult is set, in which case a result
+// that's already waiting wins over the timeout.
+func awaitResult(done <-chan struct{}, results <-chan callResult, preferResult bool) (callResult, bool) {
 	select {
-	case <-ctx.Done():
-		return nil, errors.New("request timed out")
-	case res := <-resultChan:
-		return res.result, res.err
+	case <-done:
+		if preferResult {
+			select {
+			case res := <-results:
+				return res, true
+			default:
+			}
+		}
+		return callResult{}, false
+	case res := <-results:
+		return res, true
 	}
 }
 
-// resetCircuit resets the circuit breaker to closed state
-func (cb *circuitBreaker) resetCircuit() {
-	cb.failureCount = 0
-	cb.state = Closed
-	slog.Info("Circuit reset to closed state")
+// setState transitions the circuit breaker to the given state and clears the
+// counts collected in the previous one. It reports false, leaving everything
+// untouched, if the transition guard vetoes the transition.
+func (cb *circuitBreaker) setState(state State) bool {
+	if cb.transitionGuard != nil && !cb.transitionGuard(cb.state, state) {
+		cb.log().Warn("State transition vetoed", "from", cb.state, "to", state)
+		return false
+	}
+
+	cb.transition(state)
+	return true
+}
+
+// transition moves the circuit breaker to the given state unconditionally,
+// clearing the counts collected in the previous one and notifying listeners
+func (cb *circuitBreaker) transition(state State) {
+	from := cb.state
+	cb.state = state
+	cb.publishState()
+	cb.persist()
+	cb.generation++
+	switch {
+	case from == HalfOpen && state == Open:
+		cb.reopens++
+	case state == Closed:
+		cb.reopens = 0
+	}
+	cb.clearCounts()
+	cb.openFor = 0
+	if state == Open {
+		cb.drawJitter()
+	}
+	cb.awaitingFirstProbe = state == HalfOpen
+	cb.probing = false
+	cb.lastProbe = time.Time{}
+	if state == HalfOpen {
+		cb.halfOpenSince = cb.clock.Now()
+	}
+
+	cb.sink.Incr(MetricTransitions, map[string]string{
+		"breaker": cb.name,
+		"from":    from.String(),
+		"to":      state.String(),
+	})
+	if hook := cb.onStateChange; hook != nil {
+		cb.afterUnlock(func() { hook(from, state) })
+	}
+	// Stamped here rather than on delivery, which comes after the unlock
+	change := StateChange{From: from, To: state, At: cb.clock.Now()}
+	for _, listener := range cb.listeners {
+		cb.afterUnlock(func() { listener(change) })
+	}
+}
+
+// clearCounts forgets every failure and success the trip and close decisions
+// are based on
+func (cb *circuitBreaker) clearCounts() {
+	cb.counts.clear()
+	cb.failureScore = 0
+	if cb.window != nil {
+		cb.window.clear()
+	}
+	if cb.rate != nil {
+		cb.rate.clear()
+	}
+	if cb.slowRate != nil {
+		cb.slowRate.clear()
+	}
+}
+
+// resetCircuit resets the circuit breaker to closed state, reporting whether
+// the transition went through
+func (cb *circuitBreaker) resetCircuit() bool {
+	if !cb.setState(Closed) {
+		return false
+	}
+
+	cb.log().Info("Circuit reset to closed state")
+	return true
 }
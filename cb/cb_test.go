@@ -1,11 +1,20 @@
 package cb
 
 import (
+	"context"
 	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"runtime"
+	"slices"
+	"sync"
 	"testing"
 	"time"
 )
 
+var errFailure = errors.New("failure")
+
 func TestCircuitBreaker_ClosedStateSuccess(t *testing.T) {
 	t.Parallel()
 
@@ -58,7 +67,8 @@ func TestCircuitBreaker_ClosedStateFailure(t *testing.T) {
 func TestCircuitBreaker_OpenToHalfOpen(t *testing.T) {
 	t.Parallel()
 
-	cb := NewCircuitBreaker(1, 1*time.Second, 2, 2*time.Second) // Updated argument order
+	clock := newFakeClock()
+	cb := NewCircuitBreaker(1, 1*time.Second, 2, 2*time.Second, WithClock(clock)) // Updated argument order
 
 	failFn := func() (any, error) {
 		return nil, errors.New("failure")
@@ -74,19 +84,10 @@ func TestCircuitBreaker_OpenToHalfOpen(t *testing.T) {
 	}
 
 	// Simulate time passing to trigger recovery and transition to half-open
-	time.Sleep(2 * time.Second)
-
-	// After recovery, the next call should transition to half-open, no error expected
-	_, err = cb.Call(failFn)
-	if err != nil {
-		t.Fatalf("expected no error during transition to half-open, got %v", err)
-	}
-
-	if cb.state != HalfOpen {
-		t.Fatalf("expected state half-open, got %s", cb.state)
-	}
+	clock.Advance(2 * time.Second)
 
-	// Now simulate a successful request
+	// After recovery, the next call transitions to half-open and runs as the
+	// first probe
 	successFn := func() (any, error) {
 		return 42, nil
 	}
@@ -146,7 +147,8 @@ func TestCircuitBreaker_HalfOpenStateFailure(t *testing.T) {
 func TestCircuitBreaker_OpenToHalfOpenSuccess(t *testing.T) {
 	t.Parallel()
 
-	cb := NewCircuitBreaker(1, 1*time.Second, 1, 2*time.Second) // Updated argument order
+	clock := newFakeClock()
+	cb := NewCircuitBreaker(1, 1*time.Second, 1, 2*time.Second, WithClock(clock)) // Updated argument order
 
 	// Simulate a failure to trigger transition to open
 	failFn := func() (any, error) {
@@ -164,27 +166,20 @@ func TestCircuitBreaker_OpenToHalfOpenSuccess(t *testing.T) {
 	}
 
 	// Simulate time passing to trigger recovery and transition to half-open
-	time.Sleep(2 * time.Second)
+	clock.Advance(2 * time.Second)
 
-	// First successful request should transition to half-open
+	// The first request after recovery transitions to half-open and, being the
+	// only probe needed, closes the circuit
 	successFn := func() (any, error) {
 		return 42, nil
 	}
 
-	_, err = cb.Call(successFn)
+	result, err := cb.Call(successFn)
 	if err != nil {
-		t.Fatalf("expected no error during transition to half-open, got %v", err)
-	}
-
-	// Check that the state is now half-open
-	if cb.state != HalfOpen {
-		t.Fatalf("expected state half-open, got %s", cb.state)
+		t.Fatalf("expected no error during the first probe, got %v", err)
 	}
-
-	// Another successful request should transition to closed
-	_, err = cb.Call(successFn)
-	if err != nil {
-		t.Fatalf("expected no error during successful request in half-open state, got %v", err)
+	if result != 42 {
+		t.Fatalf("expected the first probe to run the function, got %v", result)
 	}
 
 	// Ensure the breaker is now closed after enough successful requests
@@ -209,3 +204,1248 @@ func TestCircuitBreaker_RequestTimeout(t *testing.T) {
 		t.Fatalf("expected timeout error, got %v", err)
 	}
 }
+
+func TestCircuitBreaker_ConsecutiveCounts(t *testing.T) {
+	t.Parallel()
+
+	cb := NewCircuitBreaker(5, 5*time.Second, 3, 2*time.Second)
+
+	successFn := func() (any, error) {
+		return 42, nil
+	}
+	failFn := func() (any, error) {
+		return nil, errors.New("failure")
+	}
+
+	_, _ = cb.Call(successFn)
+	_, _ = cb.Call(successFn)
+
+	counts := cb.Counts()
+	if counts.ConsecutiveSuccesses != 2 || counts.ConsecutiveFailures != 0 {
+		t.Fatalf("expected 2 consecutive successes and 0 failures, got %+v", counts)
+	}
+
+	_, _ = cb.Call(failFn)
+	_, _ = cb.Call(failFn)
+
+	counts = cb.Counts()
+	if counts.ConsecutiveFailures != 2 || counts.ConsecutiveSuccesses != 0 {
+		t.Fatalf("expected 2 consecutive failures and 0 successes, got %+v", counts)
+	}
+
+	if counts.Requests != 4 || counts.TotalSuccesses != 2 || counts.TotalFailures != 2 {
+		t.Fatalf("expected 4 requests with 2 successes and 2 failures, got %+v", counts)
+	}
+
+	// A success breaks the failure streak without touching the totals
+	_, _ = cb.Call(successFn)
+
+	counts = cb.Counts()
+	if counts.ConsecutiveFailures != 0 || counts.ConsecutiveSuccesses != 1 {
+		t.Fatalf("expected the failure streak to be broken, got %+v", counts)
+	}
+
+	if counts.TotalFailures != 2 {
+		t.Fatalf("expected total failures to be 2, got %d", counts.TotalFailures)
+	}
+}
+
+func TestCircuitBreaker_CountsClearedOnTransition(t *testing.T) {
+	t.Parallel()
+
+	cb := NewCircuitBreaker(2, 5*time.Second, 3, 2*time.Second)
+
+	failFn := func() (any, error) {
+		return nil, errors.New("failure")
+	}
+
+	_, _ = cb.Call(failFn)
+	_, _ = cb.Call(failFn)
+
+	if cb.state != Open {
+		t.Fatalf("expected state open, got %s", cb.state)
+	}
+
+	if counts := cb.Counts(); counts != (Counts{}) {
+		t.Fatalf("expected counts to be cleared after tripping, got %+v", counts)
+	}
+}
+
+func TestCircuitBreaker_FirstProbeResult(t *testing.T) {
+	t.Parallel()
+
+	var probes []error
+	clock := newFakeClock()
+	cb := NewCircuitBreaker(
+		1, time.Second, 2, 2*time.Second,
+		WithClock(clock),
+		WithFirstProbeResult(func(_ any, err error) {
+			probes = append(probes, err)
+		}),
+	)
+
+	successFn := func() (any, error) {
+		return 42, nil
+	}
+	failFn := func() (any, error) {
+		return nil, errFailure
+	}
+
+	// First recovery cycle: the first probe fails
+	_, _ = cb.Call(failFn)
+	clock.Advance(2 * time.Second)
+	_, _ = cb.Call(failFn)
+
+	if len(probes) != 1 || !errors.Is(probes[0], errFailure) {
+		t.Fatalf("expected one failed first probe, got %v", probes)
+	}
+
+	// Second recovery cycle: only the first of two successful probes is reported
+	clock.Advance(2 * time.Second)
+	_, _ = cb.Call(successFn)
+	_, _ = cb.Call(successFn)
+
+	if cb.state != Closed {
+		t.Fatalf("expected state closed, got %s", cb.state)
+	}
+
+	if len(probes) != 2 || probes[1] != nil {
+		t.Fatalf("expected a successful second first probe, got %v", probes)
+	}
+}
+
+func TestCircuitBreaker_ResetHalfOpen(t *testing.T) {
+	t.Parallel()
+
+	cb := NewCircuitBreaker(1, time.Second, 2, 2*time.Second)
+	cb.setState(HalfOpen)
+
+	successFn := func() (any, error) {
+		return 42, nil
+	}
+
+	_, _ = cb.Call(successFn)
+	if cb.counts.ConsecutiveSuccesses != 1 {
+		t.Fatalf("expected 1 half-open success, got %d", cb.counts.ConsecutiveSuccesses)
+	}
+
+	cb.ResetHalfOpen()
+
+	if cb.state != HalfOpen || cb.counts != (Counts{}) {
+		t.Fatalf("expected cleared counts in half-open, got %s %+v", cb.state, cb.counts)
+	}
+
+	// Recovery needs the full number of successes again
+	_, _ = cb.Call(successFn)
+	if cb.state != HalfOpen {
+		t.Fatalf("expected state half-open after one success, got %s", cb.state)
+	}
+
+	_, _ = cb.Call(successFn)
+	if cb.state != Closed {
+		t.Fatalf("expected state closed after two successes, got %s", cb.state)
+	}
+}
+
+func TestCircuitBreaker_ResetHalfOpenWithProbeInFlight(t *testing.T) {
+	t.Parallel()
+
+	cb := NewCircuitBreaker(1, time.Second, 1, 2*time.Second, WithSingleProbe())
+	cb.setState(HalfOpen)
+
+	started, release := make(chan struct{}), make(chan struct{})
+	done := make(chan error, 1)
+	go func() {
+		_, err := cb.Call(func() (any, error) {
+			close(started)
+			<-release
+			return 42, nil
+		})
+		done <- err
+	}()
+	<-started
+
+	cb.ResetHalfOpen()
+
+	// The old probe no longer holds the slot
+	blocked, unblock := make(chan struct{}), make(chan struct{})
+	probed := make(chan error, 1)
+	go func() {
+		_, err := cb.Call(func() (any, error) {
+			close(blocked)
+			<-unblock
+			return nil, errFailure
+		})
+		probed <- err
+	}()
+	select {
+	case <-blocked:
+	case err := <-probed:
+		t.Fatalf("expected a new probe to be admitted after the reset, got %v", err)
+	}
+
+	// Nor does its success close the circuit
+	close(release)
+	if err := <-done; err != nil {
+		t.Fatalf("expected the old probe to succeed, got %v", err)
+	}
+	if cb.State() != HalfOpen {
+		t.Fatalf("expected the old probe not to count toward the new episode, got %s", cb.State())
+	}
+
+	close(unblock)
+	<-probed
+	if cb.State() != Open {
+		t.Fatalf("expected the new probe's failure to reopen the circuit, got %s", cb.State())
+	}
+}
+
+func TestCircuitBreaker_ResetHalfOpenNoop(t *testing.T) {
+	t.Parallel()
+
+	cb := NewCircuitBreaker(3, time.Second, 2, 2*time.Second)
+
+	_, _ = cb.Call(func() (any, error) {
+		return nil, errFailure
+	})
+
+	cb.ResetHalfOpen()
+
+	if cb.state != Closed || cb.counts.ConsecutiveFailures != 1 {
+		t.Fatalf("expected closed state with its failure kept, got %s %+v", cb.state, cb.counts)
+	}
+}
+
+func TestCircuitBreaker_CallClassifier(t *testing.T) {
+	t.Parallel()
+
+	const n = 50
+	cb := NewCircuitBreaker(2*n+1, time.Second, 1, 2*time.Second)
+
+	errTooMany := errors.New("429 too many requests")
+	failFn := func() (any, error) {
+		return nil, errTooMany
+	}
+	ignoreAll := func(error) bool {
+		return false
+	}
+
+	var wg sync.WaitGroup
+	for i := 0; i < n; i++ {
+		wg.Add(2)
+		go func() {
+			defer wg.Done()
+
+			// The error is still returned even though it isn't a failure
+			if _, err := cb.Call(failFn, WithCallClassifier(ignoreAll)); !errors.Is(err, errTooMany) {
+				t.Errorf("expected the original error, got %v", err)
+			}
+		}()
+		go func() {
+			defer wg.Done()
+			_, _ = cb.Call(failFn)
+		}()
+	}
+	wg.Wait()
+
+	counts := cb.Counts()
+	if counts.TotalFailures != n || counts.TotalSuccesses != n {
+		t.Fatalf("expected %d failures and %d successes, got %+v", n, n, counts)
+	}
+}
+
+func TestCircuitBreaker_TimeoutRacePreference(t *testing.T) {
+	t.Parallel()
+
+	// Both the deadline and the result are ready before waiting starts, which
+	// is the race in its purest form
+	race := func(cb *circuitBreaker) bool {
+		done := make(chan struct{})
+		close(done)
+
+		results := make(chan callResult, 1)
+		results <- callResult{result: 42}
+
+		_, ok := awaitResult(done, results, cb.preferResult)
+		return ok
+	}
+
+	preferring := NewCircuitBreaker(1, time.Second, 1, time.Second, WithTimeoutRacePreference(true))
+	for i := 0; i < 1000; i++ {
+		if !race(preferring) {
+			t.Fatalf("expected the result to win the race on attempt %d", i+1)
+		}
+	}
+
+	// Without the preference either side may win, so just check both can
+	defaulting := NewCircuitBreaker(1, time.Second, 1, time.Second)
+	wins := 0
+	for i := 0; i < 1000; i++ {
+		if race(defaulting) {
+			wins++
+		}
+	}
+	if wins == 0 || wins == 1000 {
+		t.Fatalf("expected the race to go both ways without a preference, result won %d of 1000", wins)
+	}
+}
+
+func TestCircuitBreaker_TransitionGuardVetoesClose(t *testing.T) {
+	t.Parallel()
+
+	maintenance := true
+	cb := NewCircuitBreaker(1, time.Second, 2, 2*time.Second, WithTransitionGuard(func(from, to State) bool {
+		return !(maintenance && to == Closed)
+	}))
+	cb.setState(HalfOpen)
+
+	successFn := func() (any, error) {
+		return 42, nil
+	}
+
+	_, _ = cb.Call(successFn)
+	_, _ = cb.Call(successFn)
+
+	if cb.state != HalfOpen {
+		t.Fatalf("expected the close to be vetoed, got %s", cb.state)
+	}
+
+	// The vetoed close starts a fresh round of probes rather than exhausting
+	// the budget
+	maintenance = false
+	_, _ = cb.Call(successFn)
+	if cb.state != HalfOpen {
+		t.Fatalf("expected state half-open after one fresh probe, got %s", cb.state)
+	}
+
+	if _, err := cb.Call(successFn); err != nil {
+		t.Fatalf("expected the probe to be admitted, got %v", err)
+	}
+	if cb.state != Closed {
+		t.Fatalf("expected state closed once the guard allows it, got %s", cb.state)
+	}
+}
+
+func TestCircuitBreaker_TransitionGuardVetoesRecovery(t *testing.T) {
+	t.Parallel()
+
+	vetoes := 0
+	clock := newFakeClock()
+	cb := NewCircuitBreaker(1, time.Second, 1, 2*time.Second, WithClock(clock), WithTransitionGuard(func(from, to State) bool {
+		if from == Open && to == HalfOpen && vetoes == 0 {
+			vetoes++
+			return false
+		}
+		return true
+	}))
+
+	failFn := func() (any, error) {
+		return nil, errFailure
+	}
+
+	_, _ = cb.Call(failFn)
+	clock.Advance(2 * time.Second)
+
+	_, err := cb.Call(failFn)
+	if !errors.Is(err, ErrCircuitOpen) || cb.state != Open {
+		t.Fatalf("expected the recovery to be vetoed, got state %s and error %v", cb.state, err)
+	}
+
+	// The veto restarted the recovery timer
+	clock.Advance(500 * time.Millisecond)
+	if _, err := cb.Call(failFn); !errors.Is(err, ErrCircuitOpen) {
+		t.Fatalf("expected the circuit to stay open within the new period, got %v", err)
+	}
+
+	clock.Advance(time.Second)
+	result, err := cb.Call(func() (any, error) {
+		return 42, nil
+	})
+	if result != 42 || err != nil || cb.state != Closed {
+		t.Fatalf("expected a probe to close the circuit after the new period, got state %s and result %v, %v", cb.state, result, err)
+	}
+}
+
+// severityError is an error that carries its own severity
+type severityError struct {
+	severity int
+}
+
+func (e severityError) Error() string {
+	return "severity error"
+}
+
+func (e severityError) Severity() int {
+	return e.severity
+}
+
+func TestCircuitBreaker_SeverityWeighting(t *testing.T) {
+	t.Parallel()
+
+	weight := func(err error) float64 {
+		var s interface{ Severity() int }
+		if errors.As(err, &s) {
+			return float64(s.Severity())
+		}
+		return 1
+	}
+
+	// callsToTrip counts the failures it takes to open a fresh breaker
+	callsToTrip := func(err error) int {
+		cb := NewCircuitBreaker(4, time.Second, 1, 2*time.Second, WithSeverityWeighting(weight))
+		for i := 1; i <= 10; i++ {
+			_, _ = cb.Call(func() (any, error) {
+				return nil, err
+			})
+			if cb.state == Open {
+				return i
+			}
+		}
+		return -1
+	}
+
+	if got := callsToTrip(severityError{severity: 1}); got != 4 {
+		t.Fatalf("expected low severity errors to trip after 4 failures, got %d", got)
+	}
+
+	if got := callsToTrip(severityError{severity: 2}); got != 2 {
+		t.Fatalf("expected high severity errors to trip after 2 failures, got %d", got)
+	}
+
+	if got := callsToTrip(errFailure); got != 4 {
+		t.Fatalf("expected plain errors to trip after 4 failures, got %d", got)
+	}
+}
+
+func TestCircuitBreaker_WeightedFailureThreshold(t *testing.T) {
+	t.Parallel()
+
+	errRefused := errors.New("connection refused")
+	errUnavailable := errors.New("503")
+	cb := NewCircuitBreaker(1, time.Second, 1, 2*time.Second,
+		WithWeightedFailureThreshold(2.5),
+		WithSeverityWeighting(func(err error) float64 {
+			if errors.Is(err, errRefused) {
+				return 2
+			}
+			return 0.5
+		}),
+	)
+
+	for i := 0; i < 4; i++ {
+		_, _ = cb.Call(func() (any, error) {
+			return nil, errUnavailable
+		})
+	}
+	if cb.State() != Closed {
+		t.Fatalf("expected a score of 2 to stay below 2.5, got %s", cb.State())
+	}
+
+	_, _ = cb.Call(func() (any, error) {
+		return nil, errUnavailable
+	})
+	if cb.State() != Open {
+		t.Fatalf("expected a score of 2.5 to trip the circuit, got %s", cb.State())
+	}
+
+	cb.Reset()
+	_, _ = cb.Call(func() (any, error) {
+		return nil, errRefused
+	})
+	_, _ = cb.Call(func() (any, error) {
+		return nil, errUnavailable
+	})
+	if cb.State() != Open {
+		t.Fatalf("expected a refused connection and a 503 to trip the circuit, got %s", cb.State())
+	}
+}
+
+func TestCircuitBreaker_ReconfigureEvaluates(t *testing.T) {
+	t.Parallel()
+
+	cb := NewCircuitBreaker(5, time.Second, 1, 2*time.Second)
+
+	var transitions []string
+	cb.subscribe(func(change StateChange) {
+		transitions = append(transitions, change.From.String()+"->"+change.To.String())
+	})
+
+	failFn := func() (any, error) {
+		return nil, errFailure
+	}
+	for i := 0; i < 3; i++ {
+		_, _ = cb.Call(failFn)
+	}
+
+	if cb.state != Closed {
+		t.Fatalf("expected state closed below the threshold, got %s", cb.state)
+	}
+
+	// Lowering the threshold below the failure count trips without another call
+	cb.Reconfigure(WithFailureThreshold(2))
+
+	if cb.state != Open {
+		t.Fatalf("expected state open after lowering the threshold, got %s", cb.state)
+	}
+
+	if len(transitions) != 1 || transitions[0] != "closed->open" {
+		t.Fatalf("expected a single closed->open transition, got %v", transitions)
+	}
+}
+
+// nopTracer is a CallTracer whose spans record nothing
+type nopTracer struct{}
+
+func (nopTracer) StartCall(ctx context.Context, _ string) (context.Context, CallSpan) {
+	return ctx, nopSpan{}
+}
+
+type nopSpan struct{}
+
+func (nopSpan) Rejected(error)            {}
+func (nopSpan) Tripped()                  {}
+func (nopSpan) End(State, Outcome, error) {}
+
+// Meant to be run with -race, which flags settings read without the lock
+func TestCircuitBreaker_ReconfigureDuringCalls(t *testing.T) {
+	t.Parallel()
+
+	// Sleeping rather than blocking on a channel leaves the fallback and the
+	// health check unsynchronized with Reconfigure, so -race sees any setting
+	// they read from the breaker without the lock
+	cached := func(error) (any, time.Duration, error) {
+		time.Sleep(100 * time.Microsecond)
+		return 42, time.Second, nil
+	}
+	cb := NewCircuitBreaker(1000, time.Second, 1, 2*time.Second, WithCachedFallback(cached), WithFallbackOnFailure(true))
+
+	var wg sync.WaitGroup
+	for i := 0; i < 4; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 100; j++ {
+				_, _ = cb.Call(func() (any, error) {
+					return nil, errFailure
+				})
+			}
+		}()
+	}
+
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	for i := 0; i < 100; i++ {
+		cb.Reconfigure(
+			WithIsFailure(func(err error) bool { return err != nil }),
+			WithLogger(logger),
+			WithTracer(nopTracer{}),
+			WithName(fmt.Sprintf("db-%d", i)),
+			WithCachedFallback(cached),
+			WithMaxFallbackAge(time.Duration(i)*time.Millisecond),
+			WithMetricsSink(&spySink{}),
+		)
+		time.Sleep(50 * time.Microsecond)
+	}
+	wg.Wait()
+
+	// The background prober runs the health check while it's swapped
+	check := func() error {
+		time.Sleep(100 * time.Microsecond)
+		return errFailure
+	}
+	probed := NewCircuitBreaker(1, time.Nanosecond, 1, 2*time.Second, WithHealthCheck(check))
+	if err := probed.StartProbing(time.Millisecond); err != nil {
+		t.Fatalf("expected probing to start, got %v", err)
+	}
+	defer probed.StopProbing()
+	for i := 0; i < 100; i++ {
+		probed.Trip()
+		probed.Reconfigure(WithHealthCheck(check))
+		time.Sleep(50 * time.Microsecond)
+	}
+}
+
+func TestCircuitBreaker_EvaluateNoop(t *testing.T) {
+	t.Parallel()
+
+	cb := NewCircuitBreaker(3, time.Second, 1, 2*time.Second)
+
+	_, _ = cb.Call(func() (any, error) {
+		return nil, errFailure
+	})
+
+	cb.Evaluate()
+
+	if cb.state != Closed || cb.counts.ConsecutiveFailures != 1 {
+		t.Fatalf("expected evaluation below the threshold to change nothing, got %s %+v", cb.state, cb.counts)
+	}
+}
+
+func TestCircuitBreaker_NoTimeout(t *testing.T) {
+	t.Parallel()
+
+	for _, timeout := range []time.Duration{0, -time.Second} {
+		cb := NewCircuitBreaker(1, time.Second, 1, timeout)
+
+		result, err := cb.Call(func() (any, error) {
+			time.Sleep(100 * time.Millisecond)
+			return 42, nil
+		})
+		if err != nil {
+			t.Fatalf("expected no timeout with timeout %v, got %v", timeout, err)
+		}
+
+		if val, ok := result.(int); !ok || val != 42 {
+			t.Fatalf("expected result 42, got %v", result)
+		}
+	}
+}
+
+func TestCircuitBreaker_StateAccessor(t *testing.T) {
+	t.Parallel()
+
+	cb := NewCircuitBreaker(1, time.Minute, 1, 2*time.Second)
+
+	if got := cb.State(); got != Closed {
+		t.Fatalf("expected state closed, got %s", got)
+	}
+
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(2)
+		go func() {
+			defer wg.Done()
+			_, _ = cb.Call(func() (any, error) {
+				return nil, errFailure
+			})
+		}()
+		go func() {
+			defer wg.Done()
+			if s := cb.State(); s != Closed && s != Open {
+				t.Errorf("expected closed or open, got %s", s)
+			}
+		}()
+	}
+	wg.Wait()
+
+	if got := cb.State(); got != Open {
+		t.Fatalf("expected state open, got %s", got)
+	}
+}
+
+func TestCircuitBreaker_CallContextCancelledParent(t *testing.T) {
+	t.Parallel()
+
+	cb := NewCircuitBreaker(1, time.Minute, 1, 2*time.Second)
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	called := false
+	_, err := cb.CallContext(ctx, func(context.Context) (any, error) {
+		called = true
+		return 42, nil
+	})
+	if !errors.Is(err, context.Canceled) {
+		t.Fatalf("expected context.Canceled, got %v", err)
+	}
+	if called {
+		t.Fatalf("expected fn not to be called with a cancelled parent")
+	}
+	if got := cb.Counts().Requests; got != 0 {
+		t.Fatalf("expected no request to be counted, got %d", got)
+	}
+}
+
+func TestCircuitBreaker_CallContextCancelStopsWait(t *testing.T) {
+	t.Parallel()
+
+	cb := NewCircuitBreaker(5, time.Minute, 1, time.Minute)
+	ctx, cancel := context.WithCancel(context.Background())
+
+	started := make(chan struct{})
+	go func() {
+		<-started
+		cancel()
+	}()
+
+	start := time.Now()
+	_, err := cb.CallContext(ctx, func(ctx context.Context) (any, error) {
+		close(started)
+		<-ctx.Done()
+		return nil, ctx.Err()
+	})
+	if err == nil {
+		t.Fatalf("expected an error once the parent was cancelled")
+	}
+	if elapsed := time.Since(start); elapsed > 5*time.Second {
+		t.Fatalf("expected the wait to end with the parent, took %v", elapsed)
+	}
+}
+
+func TestCircuitBreaker_CallContextPassesTimeout(t *testing.T) {
+	t.Parallel()
+
+	cb := NewCircuitBreaker(1, time.Minute, 1, 2*time.Second)
+
+	_, err := cb.CallContext(context.Background(), func(ctx context.Context) (any, error) {
+		if _, ok := ctx.Deadline(); !ok {
+			return nil, errors.New("no deadline")
+		}
+		return 42, nil
+	})
+	if err != nil {
+		t.Fatalf("expected fn to see the breaker's timeout as a deadline, got %v", err)
+	}
+}
+
+func TestCircuitBreaker_OnStateChange(t *testing.T) {
+	t.Parallel()
+
+	clock := newFakeClock()
+	var cb *circuitBreaker
+	var transitions []string
+	cb = NewCircuitBreaker(1, time.Second, 1, 2*time.Second, WithClock(clock), WithOnStateChange(
+		func(from, to State) {
+			// Would deadlock if the hook ran under the lock
+			if got := cb.State(); got != to {
+				t.Errorf("expected the hook to observe %s, got %s", to, got)
+			}
+			transitions = append(transitions, from.String()+"->"+to.String())
+		},
+	))
+
+	_, _ = cb.Call(func() (any, error) {
+		return nil, errFailure
+	})
+	clock.Advance(2 * time.Second)
+	_, _ = cb.Call(func() (any, error) { // Moves the circuit to half-open
+		return 42, nil
+	})
+	_, _ = cb.Call(func() (any, error) {
+		return 42, nil
+	})
+
+	want := []string{"closed->open", "open->half-open", "half-open->closed"}
+	if len(transitions) != len(want) {
+		t.Fatalf("expected transitions %v, got %v", want, transitions)
+	}
+	for i := range want {
+		if transitions[i] != want[i] {
+			t.Fatalf("expected transitions %v, got %v", want, transitions)
+		}
+	}
+}
+
+func TestCircuitBreaker_WithLogger(t *testing.T) {
+	t.Parallel()
+
+	h := newRecordingHandler()
+	cb := NewCircuitBreaker(5, time.Second, 1, 2*time.Second, WithLogger(slog.New(h)), WithVerbose(true))
+
+	for i := 0; i < 2; i++ {
+		_, _ = cb.Call(func() (any, error) {
+			return nil, errFailure
+		})
+	}
+	cb.Reconfigure(WithFailureThreshold(2))
+
+	var messages []string
+	for _, r := range *h.records {
+		messages = append(messages, r.Message)
+	}
+
+	// Two requests and failures, then the trip on evaluation outside any call
+	if len(messages) != 5 || messages[4] != "Failure threshold reached on evaluation, transitioning to open" {
+		t.Fatalf("expected every log to reach the injected logger, got %v", messages)
+	}
+}
+
+func TestCircuitBreaker_Verbose(t *testing.T) {
+	t.Parallel()
+
+	messages := func(verbose bool) []string {
+		h := newRecordingHandler()
+		cb := New(WithFailureThreshold(2), WithLogger(slog.New(h)), WithVerbose(verbose))
+
+		_, _ = cb.Call(func() (any, error) {
+			return 42, nil
+		})
+		for i := 0; i < 2; i++ {
+			_, _ = cb.Call(func() (any, error) {
+				return nil, errFailure
+			})
+		}
+
+		var messages []string
+		for _, r := range *h.records {
+			messages = append(messages, r.Message)
+		}
+		return messages
+	}
+
+	quiet := []string{
+		"Request failed in closed state",
+		"Request failed in closed state",
+		"Failure threshold reached, transitioning to open",
+	}
+	if got := messages(false); !slices.Equal(got, quiet) {
+		t.Fatalf("expected only failures and the transition to be logged, got %v", got)
+	}
+
+	verbose := []string{
+		"Making a request",
+		"Request succeeded in closed state",
+		"Making a request",
+		"Request failed in closed state",
+		"Making a request",
+		"Request failed in closed state",
+		"Failure threshold reached, transitioning to open",
+	}
+	if got := messages(true); !slices.Equal(got, verbose) {
+		t.Fatalf("expected every call to be logged, got %v", got)
+	}
+}
+
+func TestNew_Defaults(t *testing.T) {
+	t.Parallel()
+
+	cb := New()
+
+	if cb.state != Closed {
+		t.Fatalf("expected state closed, got %s", cb.state)
+	}
+	if cb.failureThreshold != defaultFailureThreshold {
+		t.Fatalf("expected failure threshold %d, got %d", defaultFailureThreshold, cb.failureThreshold)
+	}
+	if cb.recoveryTime != defaultRecoveryTime {
+		t.Fatalf("expected recovery time %v, got %v", defaultRecoveryTime, cb.recoveryTime)
+	}
+	if cb.halfOpenMaxRequests != defaultHalfOpenMaxRequests {
+		t.Fatalf("expected half-open max requests %d, got %d", defaultHalfOpenMaxRequests, cb.halfOpenMaxRequests)
+	}
+	if cb.timeout != 0 {
+		t.Fatalf("expected no timeout, got %v", cb.timeout)
+	}
+}
+
+func TestNew_Options(t *testing.T) {
+	t.Parallel()
+
+	cb := New(
+		WithFailureThreshold(3),
+		WithRecoveryTime(5*time.Second),
+		WithHalfOpenMaxRequests(2),
+		WithTimeout(2*time.Second),
+	)
+	old := NewCircuitBreaker(3, 5*time.Second, 2, 2*time.Second)
+
+	if cb.failureThreshold != old.failureThreshold || cb.recoveryTime != old.recoveryTime ||
+		cb.halfOpenMaxRequests != old.halfOpenMaxRequests || cb.timeout != old.timeout {
+		t.Fatalf("expected New to match the positional constructor, got %+v and %+v",
+			[]any{cb.failureThreshold, cb.recoveryTime, cb.halfOpenMaxRequests, cb.timeout},
+			[]any{old.failureThreshold, old.recoveryTime, old.halfOpenMaxRequests, old.timeout})
+	}
+}
+
+func TestCircuitBreaker_ConcurrentCallsDontSerialize(t *testing.T) {
+	t.Parallel()
+
+	const n = 50
+	const delay = 50 * time.Millisecond
+	cb := NewCircuitBreaker(1, time.Minute, 1, 5*time.Second)
+
+	start := time.Now()
+	var wg sync.WaitGroup
+	for i := 0; i < n; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_, _ = cb.Call(func() (any, error) {
+				time.Sleep(delay)
+				return 42, nil
+			})
+		}()
+	}
+	wg.Wait()
+
+	// Holding the lock through each call would take n times the delay
+	if elapsed := time.Since(start); elapsed > n*delay/4 {
+		t.Fatalf("expected concurrent calls to overlap, took %v", elapsed)
+	}
+	if got := cb.Counts().TotalSuccesses; got != n {
+		t.Fatalf("expected %d successes, got %d", n, got)
+	}
+}
+
+func TestCircuitBreaker_StaleOutcomeIgnored(t *testing.T) {
+	t.Parallel()
+
+	cb := NewCircuitBreaker(1, time.Minute, 1, 5*time.Second)
+
+	started, release := make(chan struct{}), make(chan struct{})
+	done := make(chan error)
+	go func() {
+		_, err := cb.Call(func() (any, error) {
+			close(started)
+			<-release
+			return 42, nil
+		})
+		done <- err
+	}()
+	<-started
+
+	// A failure trips the circuit while the slow call is still running
+	_, _ = cb.Call(func() (any, error) {
+		return nil, errFailure
+	})
+	if got := cb.State(); got != Open {
+		t.Fatalf("expected state open, got %s", got)
+	}
+
+	close(release)
+	if err := <-done; err != nil {
+		t.Fatalf("expected the slow call's own result, got %v", err)
+	}
+
+	if got := cb.State(); got != Open {
+		t.Fatalf("expected the late success to leave the circuit open, got %s", got)
+	}
+	if got := cb.Stats().Successes; got != 1 {
+		t.Fatalf("expected the late success to count toward the totals, got %d", got)
+	}
+}
+
+func TestCircuitBreaker_HalfOpenCountsInFlightProbes(t *testing.T) {
+	t.Parallel()
+
+	cb := NewCircuitBreaker(1, time.Minute, 1, 5*time.Second)
+	cb.setState(HalfOpen)
+
+	started, release := make(chan struct{}), make(chan struct{})
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		_, _ = cb.Call(func() (any, error) {
+			close(started)
+			<-release
+			return 42, nil
+		})
+	}()
+	<-started
+
+	_, err := cb.Call(func() (any, error) {
+		return 42, nil
+	})
+	if !errors.Is(err, ErrTooManyRequests) {
+		t.Fatalf("expected ErrTooManyRequests while the probe is in flight, got %v", err)
+	}
+
+	close(release)
+	<-done
+	if got := cb.State(); got != Closed {
+		t.Fatalf("expected the probe's success to close the circuit, got %s", got)
+	}
+}
+
+func TestCircuitBreaker_StaleOutcomeAfterRoundTrip(t *testing.T) {
+	t.Parallel()
+
+	cb := NewCircuitBreaker(1, time.Minute, 1, 5*time.Second)
+
+	started, release := make(chan struct{}), make(chan struct{})
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		_, _ = cb.Call(func() (any, error) {
+			close(started)
+			<-release
+			return nil, errFailure
+		})
+	}()
+	<-started
+
+	// The circuit trips and recovers while the call runs, ending up in the
+	// same state it was admitted in
+	cb.Trip()
+	cb.Close()
+
+	close(release)
+	<-done
+	if got := cb.State(); got != Closed {
+		t.Fatalf("expected the failure from an earlier generation not to trip the circuit, got %s", got)
+	}
+	if got := cb.Stats().Failures; got != 1 {
+		t.Fatalf("expected the stale failure to count toward the totals, got %d", got)
+	}
+}
+
+func TestCircuitBreaker_CallWithTimeout(t *testing.T) {
+	t.Parallel()
+
+	cb := NewCircuitBreaker(5, time.Minute, 1, 10*time.Millisecond)
+
+	slowFn := func() (any, error) {
+		time.Sleep(50 * time.Millisecond)
+		return 42, nil
+	}
+
+	if _, err := cb.Call(slowFn); !errors.Is(err, ErrTimeout) {
+		t.Fatalf("expected the default timeout to apply, got %v", err)
+	}
+
+	result, err := cb.CallWithTimeout(time.Second, slowFn)
+	if err != nil || result != 42 {
+		t.Fatalf("expected the longer timeout to let the call finish, got %v, %v", result, err)
+	}
+
+	if _, err := cb.CallWithTimeout(0, slowFn); !errors.Is(err, ErrTimeout) {
+		t.Fatalf("expected a zero override to fall back to the default, got %v", err)
+	}
+}
+
+// Not parallel, since other tests' goroutines would skew the count
+func TestCircuitBreaker_TimeoutsDontLeakContextAwareGoroutines(t *testing.T) {
+	cb := NewCircuitBreaker(1000, time.Minute, 1, time.Millisecond)
+	before := runtime.NumGoroutine()
+
+	for i := 0; i < 200; i++ {
+		_, err := cb.CallContext(context.Background(), func(ctx context.Context) (any, error) {
+			<-ctx.Done()
+			return nil, ctx.Err()
+		})
+		if !errors.Is(err, ErrTimeout) {
+			t.Fatalf("expected ErrTimeout, got %v", err)
+		}
+	}
+
+	// The cancelled functions return shortly after their calls time out
+	deadline := time.Now().Add(5 * time.Second)
+	for runtime.NumGoroutine() > before+5 {
+		if time.Now().After(deadline) {
+			t.Fatalf("expected the goroutines to exit, %d left over %d before", runtime.NumGoroutine(), before)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func TestCircuitBreaker_SuccessesToClose(t *testing.T) {
+	t.Parallel()
+
+	clock := newFakeClock()
+	cb := NewCircuitBreaker(1, time.Second, 5, 0, WithClock(clock), WithSuccessesToClose(3))
+	failFn := func() (any, error) { return nil, errors.New("failure") }
+	successFn := func() (any, error) { return 42, nil }
+
+	_, _ = cb.Call(failFn)
+	clock.Advance(2 * time.Second)
+
+	for i := 0; i < 2; i++ {
+		if _, err := cb.Call(successFn); err != nil {
+			t.Fatalf("expected no error, got %v", err)
+		}
+	}
+	if cb.State() != HalfOpen {
+		t.Fatalf("expected state half-open after 2 of 3 successes, got %s", cb.State())
+	}
+
+	_, _ = cb.Call(successFn)
+	if cb.State() != Closed {
+		t.Fatalf("expected state closed after 3 of 5 probes succeeded, got %s", cb.State())
+	}
+}
+
+func TestCircuitBreaker_SuccessesToClosePartialSuccess(t *testing.T) {
+	t.Parallel()
+
+	clock := newFakeClock()
+	cb := NewCircuitBreaker(1, time.Second, 5, 0, WithClock(clock), WithSuccessesToClose(3))
+	failFn := func() (any, error) { return nil, errors.New("failure") }
+	successFn := func() (any, error) { return 42, nil }
+
+	_, _ = cb.Call(failFn)
+	clock.Advance(2 * time.Second)
+
+	_, _ = cb.Call(successFn)
+	_, _ = cb.Call(successFn)
+	_, _ = cb.Call(failFn)
+	if cb.State() != Open {
+		t.Fatalf("expected a failed probe to reopen the circuit, got %s", cb.State())
+	}
+}
+
+func TestCircuitBreaker_SuccessesToCloseCappedAtHalfOpenMax(t *testing.T) {
+	t.Parallel()
+
+	clock := newFakeClock()
+	cb := NewCircuitBreaker(1, time.Second, 2, 0, WithClock(clock), WithSuccessesToClose(10))
+	successFn := func() (any, error) { return 42, nil }
+
+	_, _ = cb.Call(func() (any, error) { return nil, errors.New("failure") })
+	clock.Advance(2 * time.Second)
+
+	_, _ = cb.Call(successFn)
+	_, _ = cb.Call(successFn)
+	if cb.State() != Closed {
+		t.Fatalf("expected state closed once every admitted probe succeeded, got %s", cb.State())
+	}
+}
+
+func TestCircuitBreaker_RecoveryRunsTheFirstProbe(t *testing.T) {
+	t.Parallel()
+
+	clock := newFakeClock()
+	cb := NewCircuitBreaker(1, time.Second, 2, time.Second, WithClock(clock))
+
+	_, _ = cb.Call(func() (any, error) {
+		return nil, errFailure
+	})
+	clock.Advance(2 * time.Second)
+
+	ran := false
+	result, err := cb.Call(func() (any, error) {
+		ran = true
+		return 42, nil
+	})
+	if !ran || result != 42 || err != nil {
+		t.Fatalf("expected the first call after recovery to run, got ran %t and result %v, %v", ran, result, err)
+	}
+	if counts := cb.Counts(); cb.State() != HalfOpen || counts.ConsecutiveSuccesses != 1 {
+		t.Fatalf("expected a counted half-open probe, got state %s and counts %+v", cb.State(), counts)
+	}
+}
+
+func TestCircuitBreaker_ReadyToTrip(t *testing.T) {
+	t.Parallel()
+
+	var seen []Counts
+	cb := New(WithReadyToTrip(func(counts Counts) bool {
+		seen = append(seen, counts)
+		return counts.ConsecutiveFailures >= 5 ||
+			counts.Requests >= 5 && float64(counts.TotalFailures)/float64(counts.Requests) >= 0.6
+	}))
+
+	successFn := func() (any, error) { return 42, nil }
+	failFn := func() (any, error) { return nil, errFailure }
+
+	for _, fn := range []func() (any, error){successFn, failFn, successFn, failFn} {
+		_, _ = cb.Call(fn)
+	}
+	if cb.State() != Closed {
+		t.Fatalf("expected state closed below the minimum requests, got %s", cb.State())
+	}
+
+	_, _ = cb.Call(failFn)
+	if cb.State() != Open {
+		t.Fatalf("expected 3 failures out of 5 to trip the circuit, got %s", cb.State())
+	}
+
+	want := Counts{Requests: 5, TotalSuccesses: 2, TotalFailures: 3, ConsecutiveFailures: 2}
+	if len(seen) != 3 || seen[2] != want {
+		t.Fatalf("expected the callback to see %+v after the third failure, got %+v", want, seen)
+	}
+}
+
+func TestCircuitBreaker_CallWithState(t *testing.T) {
+	t.Parallel()
+
+	clock := newFakeClock()
+	cb := NewCircuitBreaker(1, time.Second, 2, time.Second, WithClock(clock))
+	successFn := func() (any, error) { return 42, nil }
+
+	steps := []struct {
+		fn    func() (any, error)
+		state State
+	}{
+		{func() (any, error) { return nil, errFailure }, Closed}, // Trips the circuit
+		{successFn, Open},
+		{nil, HalfOpen}, // Ends the recovery period and probes
+		{successFn, HalfOpen},
+		{successFn, Closed},
+	}
+	for i, step := range steps {
+		if step.fn == nil {
+			clock.Advance(2 * time.Second)
+			step.fn = successFn
+		}
+		_, state, _ := cb.CallWithState(step.fn)
+		if state != step.state {
+			t.Fatalf("expected call %d to be made in state %s, got %s", i, step.state, state)
+		}
+	}
+
+	cb.Disable()
+	if result, state, err := cb.CallWithState(successFn); result != 42 || state != Disabled || err != nil {
+		t.Fatalf("expected a disabled pass-through, got %v, %s, %v", result, state, err)
+	}
+}
+
+func TestCircuitBreaker_OnReject(t *testing.T) {
+	t.Parallel()
+
+	var cb *circuitBreaker
+	var rejections []int
+	cb = NewCircuitBreaker(1, time.Minute, 1, time.Second, WithOnReject(func() {
+		// Would deadlock if the hook ran under the lock
+		rejections = append(rejections, cb.Stats().Rejections)
+	}))
+
+	_, _ = cb.Call(func() (any, error) {
+		return nil, errFailure
+	})
+	if len(rejections) != 0 {
+		t.Fatalf("expected no rejections while closed, got %v", rejections)
+	}
+
+	for i := 0; i < 3; i++ {
+		_, _ = cb.Call(func() (any, error) {
+			return 42, nil
+		})
+	}
+
+	if len(rejections) != 3 || rejections[2] != 3 {
+		t.Fatalf("expected the hook to observe 3 rejections, got %v", rejections)
+	}
+	if got := cb.Stats().Rejections; got != 3 {
+		t.Fatalf("expected 3 rejections in stats, got %d", got)
+	}
+}
+
+func TestCircuitBreaker_FailureThresholdSemantics(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		threshold int
+		want      int // Failures it takes to trip the circuit
+	}{
+		{threshold: 0, want: 1},
+		{threshold: 1, want: 1},
+		{threshold: 2, want: 2},
+	}
+	for _, tt := range tests {
+		cb := New(WithFailureThreshold(tt.threshold), WithRecoveryTime(time.Minute))
+
+		if cb.State() != Closed {
+			t.Fatalf("threshold %d: expected closed before any call, got %v", tt.threshold, cb.State())
+		}
+
+		_, _ = cb.Call(func() (any, error) {
+			return 42, nil
+		})
+		if cb.State() != Closed {
+			t.Fatalf("threshold %d: expected a success to keep the circuit closed, got %v", tt.threshold, cb.State())
+		}
+
+		got := -1
+		for i := 1; i <= 3; i++ {
+			_, _ = cb.Call(func() (any, error) {
+				return nil, errFailure
+			})
+			if cb.State() == Open {
+				got = i
+				break
+			}
+		}
+		if got != tt.want {
+			t.Fatalf("threshold %d: expected the circuit to trip after %d failures, got %d", tt.threshold, tt.want, got)
+		}
+	}
+}
@@ -0,0 +1,70 @@
+// Package cbotel traces calls through circuit breakers with OpenTelemetry. It
+// lives in its own package so that users of the breaker who don't trace with
+// OpenTelemetry don't have to depend on it.
+package cbotel
+
+import (
+	"context"
+
+	"github.com/rednafi/circuit-breaker/cb"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// Attribute keys and event names set on the spans
+const (
+	KeyBreaker = attribute.Key("circuit_breaker.name")    // Name of the breaker
+	KeyState   = attribute.Key("circuit_breaker.state")   // State the call was made in
+	KeyOutcome = attribute.Key("circuit_breaker.outcome") // How the call ended
+
+	EventRejected = "circuit_breaker.rejected" // The breaker turned the call away
+	EventTripped  = "circuit_breaker.tripped"  // The call opened the circuit
+)
+
+// Tracer is a cb.CallTracer that starts OpenTelemetry spans
+type Tracer struct {
+	tracer trace.Tracer // Source of the spans
+}
+
+var _ cb.CallTracer = (*Tracer)(nil)
+
+// NewTracer returns a tracer that starts its spans with tracer. Pass it to
+// every breaker with cb.WithTracer, or use WithTracing.
+func NewTracer(tracer trace.Tracer) *Tracer {
+	return &Tracer{tracer: tracer}
+}
+
+// WithTracing starts a span with tracer around every call through the breaker
+func WithTracing(tracer trace.Tracer) cb.Option {
+	return cb.WithTracer(NewTracer(tracer))
+}
+
+// StartCall starts a span named after the breaker as a child of the span in
+// ctx, if any
+func (t *Tracer) StartCall(ctx context.Context, breaker string) (context.Context, cb.CallSpan) {
+	ctx, span := t.tracer.Start(ctx, "circuit_breaker "+breaker, trace.WithAttributes(KeyBreaker.String(breaker)))
+	return ctx, callSpan{span}
+}
+
+// callSpan is the cb.CallSpan of an OpenTelemetry span
+type callSpan struct {
+	span trace.Span
+}
+
+func (s callSpan) Rejected(err error) {
+	s.span.AddEvent(EventRejected, trace.WithAttributes(attribute.String("error", err.Error())))
+}
+
+func (s callSpan) Tripped() {
+	s.span.AddEvent(EventTripped)
+}
+
+func (s callSpan) End(state cb.State, outcome cb.Outcome, err error) {
+	s.span.SetAttributes(KeyState.String(state.String()), KeyOutcome.String(string(outcome)))
+	switch outcome {
+	case cb.OutcomeFailure, cb.OutcomeTimeout, cb.OutcomeRejected:
+		s.span.SetStatus(codes.Error, err.Error())
+	}
+	s.span.End()
+}
@@ -0,0 +1,88 @@
+package cbotel
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/rednafi/circuit-breaker/cb"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+)
+
+// attr returns the value of the span attribute key, or "" if it isn't set
+func attr(span sdktrace.ReadOnlySpan, key attribute.Key) string {
+	for _, kv := range span.Attributes() {
+		if kv.Key == key {
+			return kv.Value.Emit()
+		}
+	}
+	return ""
+}
+
+// events returns the names of the span's events
+func events(span sdktrace.ReadOnlySpan) []string {
+	var names []string
+	for _, e := range span.Events() {
+		names = append(names, e.Name)
+	}
+	return names
+}
+
+func TestWithTracing(t *testing.T) {
+	t.Parallel()
+
+	recorder := tracetest.NewSpanRecorder()
+	provider := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder))
+	breaker := cb.New(
+		cb.WithName("db"),
+		cb.WithFailureThreshold(1),
+		cb.WithRecoveryTime(time.Minute),
+		WithTracing(provider.Tracer("test")),
+	)
+
+	_, _ = breaker.Call(func() (any, error) {
+		return 42, nil
+	})
+	_, _ = breaker.Call(func() (any, error) {
+		return nil, errors.New("failure")
+	})
+	_, _ = breaker.Call(func() (any, error) {
+		return 42, nil
+	})
+
+	spans := recorder.Ended()
+	if len(spans) != 3 {
+		t.Fatalf("expected 3 spans, got %d", len(spans))
+	}
+
+	checks := []struct {
+		state, outcome string
+		events         []string
+		status         codes.Code
+	}{
+		{"closed", "success", nil, codes.Unset},
+		{"closed", "failure", []string{EventTripped}, codes.Error},
+		{"open", "rejected", []string{EventRejected}, codes.Error},
+	}
+	for i, c := range checks {
+		span := spans[i]
+		if got := attr(span, KeyBreaker); got != "db" {
+			t.Fatalf("span %d: expected breaker db, got %q", i, got)
+		}
+		if got := attr(span, KeyState); got != c.state {
+			t.Fatalf("span %d: expected state %s, got %q", i, c.state, got)
+		}
+		if got := attr(span, KeyOutcome); got != c.outcome {
+			t.Fatalf("span %d: expected outcome %s, got %q", i, c.outcome, got)
+		}
+		if got := events(span); len(got) != len(c.events) || len(got) > 0 && got[0] != c.events[0] {
+			t.Fatalf("span %d: expected events %v, got %v", i, c.events, got)
+		}
+		if got := span.Status().Code; got != c.status {
+			t.Fatalf("span %d: expected status %s, got %s", i, c.status, got)
+		}
+	}
+}
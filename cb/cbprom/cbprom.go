@@ -0,0 +1,106 @@
+// Package cbprom exports the metrics of circuit breakers to Prometheus. It
+// lives in its own package so that users of the breaker who don't use
+// Prometheus don't have to depend on its client.
+package cbprom
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/rednafi/circuit-breaker/cb"
+)
+
+// states lists the states reported by the state gauge
+var states = []cb.State{cb.Closed, cb.Open, cb.HalfOpen}
+
+// Sink is a cb.MetricsSink that feeds Prometheus collectors
+type Sink struct {
+	calls       *prometheus.CounterVec   // Calls by breaker, state and outcome
+	timeouts    *prometheus.CounterVec   // Timed out calls by breaker
+	duration    *prometheus.HistogramVec // Duration of completed calls by breaker and state
+	transitions *prometheus.CounterVec   // State transitions by breaker, from and to
+	failures    *prometheus.CounterVec   // Calls that got no result by breaker and reason
+	fallbacks   *prometheus.CounterVec   // Calls handed to the fallback by breaker and result
+	state       *prometheus.GaugeVec     // 1 for the current state of each breaker
+}
+
+var _ cb.MetricsSink = (*Sink)(nil)
+
+// NewSink creates the breaker collectors and registers them with reg. Pass
+// the sink to every breaker with cb.WithMetricsSink; their series are told
+// apart by the breaker's name.
+func NewSink(reg prometheus.Registerer) (*Sink, error) {
+	s := &Sink{
+		calls: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: cb.MetricCalls + "_total",
+			Help: "Calls through the circuit breaker by state and outcome.",
+		}, []string{"breaker", "state", "outcome"}),
+		timeouts: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: cb.MetricTimeouts + "_total",
+			Help: "Calls through the circuit breaker that timed out.",
+		}, []string{"breaker"}),
+		duration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Name:    cb.MetricDuration,
+			Help:    "Duration of calls through the circuit breaker that ran to completion.",
+			Buckets: prometheus.DefBuckets,
+		}, []string{"breaker", "state"}),
+		transitions: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: cb.MetricTransitions + "_total",
+			Help: "State transitions of the circuit breaker.",
+		}, []string{"breaker", "from", "to"}),
+		failures: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: cb.MetricFailures + "_total",
+			Help: "Calls through the circuit breaker that got no result, by reason.",
+		}, []string{"breaker", "reason"}),
+		fallbacks: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: cb.MetricFallbacks + "_total",
+			Help: "Calls through the circuit breaker handed to the fallback, by result.",
+		}, []string{"breaker", "result"}),
+		state: prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Name: "circuit_breaker_state",
+			Help: "Current state of the circuit breaker, 1 for the active state.",
+		}, []string{"breaker", "state"}),
+	}
+
+	for _, c := range []prometheus.Collector{s.calls, s.timeouts, s.duration, s.transitions, s.failures, s.fallbacks, s.state} {
+		if err := reg.Register(c); err != nil {
+			return nil, err
+		}
+	}
+	return s, nil
+}
+
+// Incr counts an event of the named series, ignoring unknown ones
+func (s *Sink) Incr(name string, labels map[string]string) {
+	breaker := labels["breaker"]
+	switch name {
+	case cb.MetricCalls:
+		s.calls.WithLabelValues(breaker, labels["state"], labels["outcome"]).Inc()
+		s.setState(breaker, labels["state"])
+	case cb.MetricTimeouts:
+		s.timeouts.WithLabelValues(breaker).Inc()
+	case cb.MetricTransitions:
+		s.transitions.WithLabelValues(breaker, labels["from"], labels["to"]).Inc()
+		s.setState(breaker, labels["to"])
+	case cb.MetricFailures:
+		s.failures.WithLabelValues(breaker, labels["reason"]).Inc()
+	case cb.MetricFallbacks:
+		s.fallbacks.WithLabelValues(breaker, labels["result"]).Inc()
+	}
+}
+
+// Observe records a value of the named series, ignoring unknown ones
+func (s *Sink) Observe(name string, value float64, labels map[string]string) {
+	if name == cb.MetricDuration {
+		s.duration.WithLabelValues(labels["breaker"], labels["state"]).Observe(value)
+	}
+}
+
+// setState points the state gauge of breaker at state
+func (s *Sink) setState(breaker, state string) {
+	for _, st := range states {
+		value := 0.0
+		if st.String() == state {
+			value = 1
+		}
+		s.state.WithLabelValues(breaker, st.String()).Set(value)
+	}
+}
@@ -0,0 +1,103 @@
+package cbprom
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/rednafi/circuit-breaker/cb"
+)
+
+func TestSink(t *testing.T) {
+	t.Parallel()
+
+	reg := prometheus.NewRegistry()
+	sink, err := NewSink(reg)
+	if err != nil {
+		t.Fatalf("expected the collectors to register, got %v", err)
+	}
+
+	breaker := cb.New(
+		cb.WithName("db"),
+		cb.WithFailureThreshold(1),
+		cb.WithRecoveryTime(time.Minute),
+		cb.WithTimeout(time.Second),
+		cb.WithMetricsSink(sink),
+	)
+
+	_, _ = breaker.Call(func() (any, error) {
+		return 42, nil
+	})
+	_, _ = breaker.Call(func() (any, error) {
+		return nil, errors.New("failure")
+	})
+	_, _ = breaker.Call(func() (any, error) {
+		return 42, nil
+	})
+
+	checks := []struct {
+		collector prometheus.Collector
+		want      float64
+	}{
+		{sink.calls.WithLabelValues("db", "closed", "success"), 1},
+		{sink.calls.WithLabelValues("db", "closed", "failure"), 1},
+		{sink.calls.WithLabelValues("db", "open", "rejected"), 1},
+		{sink.transitions.WithLabelValues("db", "closed", "open"), 1},
+		{sink.failures.WithLabelValues("db", "error"), 1},
+		{sink.failures.WithLabelValues("db", "rejected"), 1},
+		{sink.state.WithLabelValues("db", "open"), 1},
+		{sink.state.WithLabelValues("db", "closed"), 0},
+	}
+	for _, c := range checks {
+		if got := testutil.ToFloat64(c.collector); got != c.want {
+			t.Fatalf("expected %g, got %g", c.want, got)
+		}
+	}
+
+	if got := testutil.CollectAndCount(sink.duration); got != 1 {
+		t.Fatalf("expected a duration series, got %d", got)
+	}
+}
+
+func TestNewSink_DuplicateRegistration(t *testing.T) {
+	t.Parallel()
+
+	reg := prometheus.NewRegistry()
+	if _, err := NewSink(reg); err != nil {
+		t.Fatalf("expected the collectors to register, got %v", err)
+	}
+	if _, err := NewSink(reg); err == nil {
+		t.Fatalf("expected registering twice to fail")
+	}
+}
+
+func TestSink_Fallbacks(t *testing.T) {
+	t.Parallel()
+
+	sink, err := NewSink(prometheus.NewRegistry())
+	if err != nil {
+		t.Fatalf("expected the collectors to register, got %v", err)
+	}
+
+	breaker := cb.New(
+		cb.WithName("db"),
+		cb.WithFailureThreshold(1),
+		cb.WithRecoveryTime(time.Minute),
+		cb.WithMetricsSink(sink),
+		cb.WithFallback(func(err error) (any, error) {
+			return "cached", nil
+		}),
+	)
+	_, _ = breaker.Call(func() (any, error) {
+		return nil, errors.New("failure")
+	})
+	_, _ = breaker.Call(func() (any, error) {
+		return 42, nil
+	})
+
+	if got := testutil.ToFloat64(sink.fallbacks.WithLabelValues("db", "served")); got != 1 {
+		t.Fatalf("expected 1 served fallback, got %g", got)
+	}
+}
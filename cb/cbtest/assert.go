@@ -0,0 +1,21 @@
+package cbtest
+
+import (
+	"testing"
+
+	"github.com/rednafi/circuit-breaker/cb"
+)
+
+// Breaker is the part of a circuit breaker the assertions look at
+type Breaker interface {
+	State() cb.State
+}
+
+// AssertState fails the test if breaker isn't in the want state
+func AssertState(t testing.TB, breaker Breaker, want cb.State) {
+	t.Helper()
+
+	if got := breaker.State(); got != want {
+		t.Fatalf("expected circuit state %s, got %s", want, got)
+	}
+}
@@ -0,0 +1,35 @@
+package cbtest
+
+import (
+	"testing"
+
+	"github.com/rednafi/circuit-breaker/cb"
+)
+
+// recorder is a testing.TB that records failures instead of stopping the test
+type recorder struct {
+	testing.TB
+	failed string
+}
+
+func (r *recorder) Helper() {}
+
+func (r *recorder) Fatalf(format string, args ...any) {
+	r.failed = format
+}
+
+func TestAssertState(t *testing.T) {
+	t.Parallel()
+
+	breaker := cb.New()
+	rec := &recorder{TB: t}
+
+	AssertState(rec, breaker, cb.Closed)
+	if rec.failed != "" {
+		t.Fatalf("expected the assertion to pass")
+	}
+	AssertState(rec, breaker, cb.Open)
+	if rec.failed == "" {
+		t.Fatalf("expected the assertion to fail")
+	}
+}
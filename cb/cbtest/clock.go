@@ -0,0 +1,79 @@
+// Package cbtest provides helpers for testing code that uses circuit breakers
+package cbtest
+
+import (
+	"sync"
+	"time"
+)
+
+// FakeClock is a cb.Clock that only moves when told to, so tests can cross a
+// breaker's recovery time without sleeping. It's also a cb.TimerClock, so
+// moving it wakes background work such as StartProbing. It's safe for
+// concurrent use.
+type FakeClock struct {
+	mu      sync.Mutex // Guards now and waiters
+	now     time.Time  // Time reported by Now
+	waiters []waiter   // Channels returned by After that haven't fired yet
+}
+
+// waiter is a pending After call
+type waiter struct {
+	at time.Time      // Time the waiter fires at
+	ch chan time.Time // Receives the clock's time once it reaches at
+}
+
+// NewFakeClock returns a FakeClock stopped at now
+func NewFakeClock(now time.Time) *FakeClock {
+	return &FakeClock{now: now}
+}
+
+// Now returns the clock's current time
+func (c *FakeClock) Now() time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return c.now
+}
+
+// Advance moves the clock forward by d
+func (c *FakeClock) Advance(d time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.now = c.now.Add(d)
+	c.fire()
+}
+
+// Set moves the clock to now, which may be in the past
+func (c *FakeClock) Set(now time.Time) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.now = now
+	c.fire()
+}
+
+// After returns a channel that receives the clock's time once it has been
+// moved on by d
+func (c *FakeClock) After(d time.Duration) <-chan time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	ch := make(chan time.Time, 1)
+	c.waiters = append(c.waiters, waiter{at: c.now.Add(d), ch: ch})
+	c.fire()
+	return ch
+}
+
+// fire wakes the waiters that are due, with c.mu held
+func (c *FakeClock) fire() {
+	pending := c.waiters[:0]
+	for _, w := range c.waiters {
+		if w.at.After(c.now) {
+			pending = append(pending, w)
+			continue
+		}
+		w.ch <- c.now
+	}
+	c.waiters = pending
+}
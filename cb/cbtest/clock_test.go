@@ -0,0 +1,124 @@
+package cbtest
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/rednafi/circuit-breaker/cb"
+)
+
+func TestFakeClock(t *testing.T) {
+	t.Parallel()
+
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	clock := NewFakeClock(start)
+
+	clock.Advance(time.Minute)
+	if got := clock.Now(); !got.Equal(start.Add(time.Minute)) {
+		t.Fatalf("expected %v, got %v", start.Add(time.Minute), got)
+	}
+
+	clock.Set(start)
+	if got := clock.Now(); !got.Equal(start) {
+		t.Fatalf("expected %v, got %v", start, got)
+	}
+}
+
+func TestFakeClock_After(t *testing.T) {
+	t.Parallel()
+
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	clock := NewFakeClock(start)
+	ch := clock.After(time.Minute)
+
+	clock.Advance(59 * time.Second)
+	select {
+	case got := <-ch:
+		t.Fatalf("expected no wake-up before a minute, got %v", got)
+	default:
+	}
+
+	clock.Advance(time.Second)
+	select {
+	case got := <-ch:
+		if !got.Equal(start.Add(time.Minute)) {
+			t.Fatalf("expected %v, got %v", start.Add(time.Minute), got)
+		}
+	default:
+		t.Fatalf("expected a wake-up once a minute had passed")
+	}
+
+	select {
+	case <-clock.After(0):
+	default:
+		t.Fatalf("expected a zero wait to fire at once")
+	}
+}
+
+func TestFakeClock_DrivesProbing(t *testing.T) {
+	t.Parallel()
+
+	clock := NewFakeClock(time.Now())
+	checks := make(chan struct{}, 1)
+	breaker := cb.New(
+		cb.WithFailureThreshold(1),
+		cb.WithRecoveryTime(time.Minute),
+		cb.WithClock(clock),
+		cb.WithHealthCheck(func() error {
+			select {
+			case checks <- struct{}{}:
+			default:
+			}
+			return nil
+		}),
+	)
+	breaker.Trip()
+	if err := breaker.StartProbing(time.Hour); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	defer breaker.StopProbing()
+
+	// The probe loop waits on the clock, so only moving it runs the check.
+	// Keep moving it in case the loop hadn't started waiting yet.
+	deadline := time.Now().Add(time.Second)
+	for breaker.State() != cb.Closed {
+		if time.Now().After(deadline) {
+			t.Fatalf("expected an hour on the fake clock to run the health check, got %s", breaker.State())
+		}
+		clock.Advance(time.Hour)
+		time.Sleep(time.Millisecond)
+	}
+	select {
+	case <-checks:
+	default:
+		t.Fatalf("expected the health check to close the circuit")
+	}
+}
+
+func TestFakeClock_DrivesRecovery(t *testing.T) {
+	t.Parallel()
+
+	clock := NewFakeClock(time.Now())
+	breaker := cb.New(
+		cb.WithFailureThreshold(1),
+		cb.WithRecoveryTime(time.Hour),
+		cb.WithHalfOpenMaxRequests(2),
+		cb.WithClock(clock),
+	)
+
+	_, _ = breaker.Call(func() (any, error) {
+		return nil, errors.New("failure")
+	})
+	if got := breaker.State(); got != cb.Open {
+		t.Fatalf("expected state open, got %s", got)
+	}
+
+	clock.Advance(time.Hour + time.Second)
+	_, _ = breaker.Call(func() (any, error) {
+		return 42, nil
+	})
+	if got := breaker.State(); got != cb.HalfOpen {
+		t.Fatalf("expected state half-open after the recovery time, got %s", got)
+	}
+}
@@ -0,0 +1,62 @@
+package cbtest
+
+import (
+	"errors"
+	"sync"
+)
+
+// ErrScriptExhausted is returned by a ScriptedFn called more times than it has
+// steps
+var ErrScriptExhausted = errors.New("cbtest: scripted function called too many times")
+
+// Step is one pre-programmed outcome of a ScriptedFn
+type Step struct {
+	Result any   // Result returned by the call
+	Err    error // Error returned by the call
+}
+
+// Ok returns a step that succeeds with result
+func Ok(result any) Step {
+	return Step{Result: result}
+}
+
+// Fail returns a step that fails with err
+func Fail(err error) Step {
+	return Step{Err: err}
+}
+
+// ScriptedFn stands in for a breaker-wrapped function, returning its steps in
+// order, one per call, then ErrScriptExhausted. It's safe for concurrent use.
+type ScriptedFn struct {
+	mu    sync.Mutex // Guards calls
+	steps []Step     // Outcomes to return, in order
+	calls int        // Number of calls made so far
+}
+
+// NewScriptedFn returns a ScriptedFn that plays back steps
+func NewScriptedFn(steps ...Step) *ScriptedFn {
+	return &ScriptedFn{steps: steps}
+}
+
+// Call returns the next step's result and error. Pass it to the breaker as
+// the function to run, e.g. breaker.Call(fn.Call).
+func (f *ScriptedFn) Call() (any, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	f.calls++
+	if f.calls > len(f.steps) {
+		return nil, ErrScriptExhausted
+	}
+	step := f.steps[f.calls-1]
+	return step.Result, step.Err
+}
+
+// Calls returns how many times Call ran, which is how many calls the breaker
+// let through
+func (f *ScriptedFn) Calls() int {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	return f.calls
+}
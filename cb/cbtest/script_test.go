@@ -0,0 +1,53 @@
+package cbtest
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/rednafi/circuit-breaker/cb"
+)
+
+func TestScriptedFn(t *testing.T) {
+	t.Parallel()
+
+	errFailure := errors.New("failure")
+	fn := NewScriptedFn(Ok(42), Fail(errFailure))
+
+	if result, err := fn.Call(); result != 42 || err != nil {
+		t.Fatalf("expected 42, got %v, %v", result, err)
+	}
+	if _, err := fn.Call(); !errors.Is(err, errFailure) {
+		t.Fatalf("expected the scripted failure, got %v", err)
+	}
+	if _, err := fn.Call(); !errors.Is(err, ErrScriptExhausted) {
+		t.Fatalf("expected ErrScriptExhausted, got %v", err)
+	}
+	if got := fn.Calls(); got != 3 {
+		t.Fatalf("expected 3 calls, got %d", got)
+	}
+}
+
+func TestScriptedFn_DrivesBreaker(t *testing.T) {
+	t.Parallel()
+
+	clock := NewFakeClock(time.Now())
+	breaker := cb.New(cb.WithFailureThreshold(2), cb.WithRecoveryTime(time.Minute), cb.WithClock(clock))
+	fn := NewScriptedFn(Fail(errors.New("failure")), Fail(errors.New("failure")), Ok(42))
+
+	_, _ = breaker.Call(fn.Call)
+	AssertState(t, breaker, cb.Closed)
+	_, _ = breaker.Call(fn.Call)
+	AssertState(t, breaker, cb.Open)
+
+	_, _ = breaker.Call(fn.Call)
+	if got := fn.Calls(); got != 2 {
+		t.Fatalf("expected the open circuit to block the third call, got %d calls", got)
+	}
+
+	clock.Advance(time.Minute + time.Second)
+	if result, err := breaker.Call(fn.Call); result != 42 || err != nil {
+		t.Fatalf("expected the probe to return 42, got %v, %v", result, err)
+	}
+	AssertState(t, breaker, cb.Closed)
+}
@@ -0,0 +1,57 @@
+// Package cbutil adapts functions of other shapes to circuit breakers, so a
+// protected function can be called with its own arguments instead of through
+// a closure at every call site
+package cbutil
+
+import (
+	"fmt"
+
+	"github.com/rednafi/circuit-breaker/cb"
+)
+
+// Caller is a circuit breaker, or anything else that runs functions like one
+type Caller interface {
+	Call(fn func() (any, error), opts ...cb.CallOption) (any, error)
+}
+
+// Wrap0 returns a function that calls fn through breaker
+func Wrap0[R any](breaker Caller, fn func() (R, error), opts ...cb.CallOption) func() (R, error) {
+	return func() (R, error) {
+		return resultAs[R](breaker.Call(func() (any, error) {
+			return fn()
+		}, opts...))
+	}
+}
+
+// Wrap1 returns a function that calls fn through breaker with its argument
+func Wrap1[A, R any](breaker Caller, fn func(A) (R, error), opts ...cb.CallOption) func(A) (R, error) {
+	return func(a A) (R, error) {
+		return resultAs[R](breaker.Call(func() (any, error) {
+			return fn(a)
+		}, opts...))
+	}
+}
+
+// Wrap2 returns a function that calls fn through breaker with its arguments
+func Wrap2[A, B, R any](breaker Caller, fn func(A, B) (R, error), opts ...cb.CallOption) func(A, B) (R, error) {
+	return func(a A, b B) (R, error) {
+		return resultAs[R](breaker.Call(func() (any, error) {
+			return fn(a, b)
+		}, opts...))
+	}
+}
+
+// resultAs converts the breaker's result back to an R, returning the zero
+// value and an error wrapping cb.ErrResultType if a fallback made it
+// something else
+func resultAs[R any](result any, err error) (R, error) {
+	var zero R
+	if result == nil {
+		return zero, err
+	}
+	val, ok := result.(R)
+	if !ok {
+		return zero, fmt.Errorf("%w: got %T, want %T", cb.ErrResultType, result, zero)
+	}
+	return val, err
+}
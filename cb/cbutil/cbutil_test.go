@@ -0,0 +1,65 @@
+package cbutil
+
+import (
+	"errors"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/rednafi/circuit-breaker/cb"
+)
+
+func TestWrap1(t *testing.T) {
+	t.Parallel()
+
+	breaker := cb.New(cb.WithFailureThreshold(1), cb.WithRecoveryTime(time.Minute))
+	atoi := Wrap1(breaker, strconv.Atoi)
+
+	n, err := atoi("42")
+	if err != nil || n != 42 {
+		t.Fatalf("expected 42, got %d, %v", n, err)
+	}
+
+	if _, err := atoi("x"); !errors.Is(err, strconv.ErrSyntax) {
+		t.Fatalf("expected the syntax error, got %v", err)
+	}
+	n, err = atoi("42")
+	if !errors.Is(err, cb.ErrCircuitOpen) || n != 0 {
+		t.Fatalf("expected the open circuit to reject the call with 0, got %d, %v", n, err)
+	}
+}
+
+func TestWrap2(t *testing.T) {
+	t.Parallel()
+
+	errDivide := errors.New("division by zero")
+	divide := Wrap2(cb.New(), func(a, b int) (int, error) {
+		if b == 0 {
+			return 0, errDivide
+		}
+		return a / b, nil
+	})
+
+	if q, err := divide(84, 2); err != nil || q != 42 {
+		t.Fatalf("expected 42, got %d, %v", q, err)
+	}
+	if _, err := divide(1, 0); !errors.Is(err, errDivide) {
+		t.Fatalf("expected the division error, got %v", err)
+	}
+}
+
+func TestWrap0_ResultType(t *testing.T) {
+	t.Parallel()
+
+	breaker := cb.New(cb.WithFailureThreshold(1), cb.WithRecoveryTime(time.Minute), cb.WithFallback(func(error) (any, error) {
+		return "cached", nil
+	}))
+	fetch := Wrap0(breaker, func() (int, error) {
+		return 0, errors.New("failure")
+	})
+
+	_, _ = fetch()
+	if _, err := fetch(); !errors.Is(err, cb.ErrResultType) {
+		t.Fatalf("expected ErrResultType for a fallback of the wrong type, got %v", err)
+	}
+}
@@ -0,0 +1,39 @@
+package cb
+
+// BreakerChain guards a call with several breakers at once, such as a
+// per-instance breaker inside a per-cluster one
+type BreakerChain struct {
+	breakers []*circuitBreaker // Outermost first
+}
+
+// Chain returns a chain that calls through breakers in order, the first being
+// the outermost. A call runs only if every breaker admits it, and stops at the
+// first one that rejects it.
+//
+// Each breaker records the outcome it sees. The outermost sees what the caller
+// sees, and an inner breaker's rejection reaches the breakers outside it as an
+// ordinary error, counted as a failure like any other. Breakers that shouldn't
+// trip because of an inner one can exclude ErrCircuitOpen with their
+// classifier.
+func Chain(breakers ...*circuitBreaker) *BreakerChain {
+	return &BreakerChain{breakers: breakers}
+}
+
+// Call runs fn through every breaker of the chain. The call options apply to
+// the innermost breaker, which is the one that runs fn.
+func (ch *BreakerChain) Call(fn func() (any, error), opts ...CallOption) (any, error) {
+	if len(ch.breakers) == 0 {
+		return fn()
+	}
+
+	next := func() (any, error) {
+		return ch.breakers[len(ch.breakers)-1].Call(fn, opts...)
+	}
+	for i := len(ch.breakers) - 2; i >= 0; i-- {
+		cb, inner := ch.breakers[i], next
+		next = func() (any, error) {
+			return cb.Call(inner)
+		}
+	}
+	return next()
+}
@@ -0,0 +1,64 @@
+package cb
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestChain_DifferentThresholds(t *testing.T) {
+	t.Parallel()
+
+	cluster := NewCircuitBreaker(3, time.Minute, 1, time.Second, WithName("cluster"))
+	instance := NewCircuitBreaker(1, time.Minute, 1, time.Second, WithName("instance"))
+	chain := Chain(cluster, instance)
+
+	result, err := chain.Call(func() (any, error) {
+		return 42, nil
+	})
+	if err != nil || result != 42 {
+		t.Fatalf("expected 42, got %v, %v", result, err)
+	}
+
+	_, err = chain.Call(func() (any, error) {
+		return nil, errFailure
+	})
+	if !errors.Is(err, errFailure) {
+		t.Fatalf("expected the failure, got %v", err)
+	}
+	if instance.State() != Open || cluster.State() != Closed {
+		t.Fatalf("expected only the instance breaker to trip, got instance %s, cluster %s", instance.State(), cluster.State())
+	}
+
+	ran := false
+	for i := 0; i < 2; i++ {
+		_, err = chain.Call(func() (any, error) {
+			ran = true
+			return 42, nil
+		})
+		if !errors.Is(err, ErrCircuitOpen) {
+			t.Fatalf("expected the open instance breaker to reject the call, got %v", err)
+		}
+	}
+	if ran {
+		t.Fatalf("expected the function not to run")
+	}
+
+	// The instance breaker's rejections count as failures of the cluster
+	if cluster.State() != Open {
+		t.Fatalf("expected the cluster breaker to trip, got %s", cluster.State())
+	}
+	if got := instance.Stats().Rejections; got != 2 {
+		t.Fatalf("expected the instance breaker to reject 2 calls, got %d", got)
+	}
+
+	_, _ = chain.Call(func() (any, error) {
+		return 42, nil
+	})
+	if got := instance.Stats().Rejections; got != 2 {
+		t.Fatalf("expected the open cluster breaker to short-circuit the instance breaker, got %d rejections", got)
+	}
+	if got := cluster.Stats().Rejections; got != 1 {
+		t.Fatalf("expected the cluster breaker to reject 1 call, got %d", got)
+	}
+}
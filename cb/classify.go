@@ -0,0 +1,128 @@
+package cb
+
+import (
+	"errors"
+	"time"
+)
+
+// Classification describes how an error returned by the protected function
+// affects the breaker
+type Classification struct {
+	IsFailure bool    // Whether the error counts as a failure at all
+	Retryable bool    // Whether the dependency asked to be retried shortly
+	Weight    float64 // Weight of the failure toward the threshold, zero means 1
+}
+
+// WithClassifier sets a classifier that decides for each error whether it's a
+// failure, whether it's retryable, and how much it weighs toward the trip
+// threshold. It takes precedence over WithSeverityWeighting. A per-call
+// classifier still overrides whether an error is a failure.
+func WithClassifier(classify func(err error) Classification) Option {
+	return func(cb *circuitBreaker) {
+		cb.classifier = classify
+	}
+}
+
+// WithIsFailure sets the predicate that decides whether an error counts as a
+// failure. Errors it rejects, such as a not found or a validation error that's
+// the caller's fault, are returned to the caller but leave the breaker alone.
+// Defaults to counting every error. A classifier set with WithClassifier takes
+// precedence.
+func WithIsFailure(isFailure func(err error) bool) Option {
+	return func(cb *circuitBreaker) {
+		cb.isFailure = isFailure
+		if isFailure == nil {
+			cb.isFailure = isAnyError
+		}
+	}
+}
+
+// WithTimeoutWeight makes each timeout count toward the trip threshold with
+// weight instead of like any other failure, since a timeout is often the
+// stronger sign of an unhealthy dependency. A weight of 2 counts timeouts
+// double, and a weight of at least the threshold trips the circuit on the
+// first one. Timeouts reach classifiers as ErrTimeout.
+func WithTimeoutWeight(weight float64) Option {
+	return func(cb *circuitBreaker) {
+		cb.timeoutWeight = weight
+	}
+}
+
+// WithRetryableRecovery makes the breaker honor retryable failures, such as a
+// 503 with Retry-After. They don't count toward tripping in closed state, and
+// a retryable failure in half-open reopens the circuit for d rather than the
+// full recovery time. Without it, retryable failures are treated like any
+// other.
+func WithRetryableRecovery(d time.Duration) Option {
+	return func(cb *circuitBreaker) {
+		cb.retryableRecovery = d
+	}
+}
+
+// classify decides how the outcome of c affects the breaker
+func (cb *circuitBreaker) classify(c *call, err error) Classification {
+	if err == nil {
+		return Classification{}
+	}
+
+	var cls Classification
+	switch {
+	case cb.classifier != nil:
+		cls = cb.classifier(err)
+	case cb.failureWeight != nil:
+		cls = Classification{IsFailure: true, Weight: cb.failureWeight(err)}
+	default:
+		cls = Classification{IsFailure: true}
+	}
+
+	if c.classified {
+		cls.IsFailure = c.isFailure(err)
+	} else if cb.classifier == nil {
+		cls.IsFailure = cb.isFailure(err)
+	}
+
+	if cb.timeoutWeight > 0 && errors.Is(err, ErrTimeout) {
+		cls.Weight = cb.timeoutWeight
+	}
+	if cls.Weight == 0 {
+		cls.Weight = 1
+	}
+	cls.Retryable = cls.Retryable && cb.retryableRecovery > 0
+	return cls
+}
+
+// weighted reports whether failures count toward the threshold by weight
+// rather than by number
+func (cb *circuitBreaker) weighted() bool {
+	return cb.classifier != nil || cb.failureWeight != nil || cb.timeoutWeight > 0
+}
+
+// tripScore returns the failure score that trips the circuit
+func (cb *circuitBreaker) tripScore() float64 {
+	if cb.weighted() && cb.weightedThreshold > 0 {
+		return cb.weightedThreshold
+	}
+	return float64(cb.threshold())
+}
+
+// threshold returns the failure threshold, clamped to at least 1 so that a
+// zero or negative setting can't trip the circuit before anything failed
+func (cb *circuitBreaker) threshold() int {
+	return max(cb.failureThreshold, 1)
+}
+
+// recoveryWindow returns how long the circuit stays open before probing
+func (cb *circuitBreaker) recoveryWindow() time.Duration {
+	if cb.openFor > 0 {
+		return cb.openFor + cb.jitter
+	}
+	return cb.backedOff() + cb.jitter
+}
+
+// startRecovery restarts the open circuit's recovery period from now, fixing
+// the deadline it recovers at
+func (cb *circuitBreaker) startRecovery() {
+	cb.lastFailureTime = cb.clock.Now()
+	cb.openUntil = cb.lastFailureTime.Add(cb.recoveryWindow())
+	cb.publishOpen()
+}
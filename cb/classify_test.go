@@ -0,0 +1,209 @@
+package cb
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+var (
+	errUnavailable = errors.New("503 service unavailable")
+	errCallerFault = errors.New("invalid argument")
+)
+
+// classifyUnavailable treats 503s as retryable failures and the rest as hard
+func classifyUnavailable(err error) Classification {
+	return Classification{IsFailure: true, Retryable: errors.Is(err, errUnavailable)}
+}
+
+func TestCircuitBreaker_RetryableFailuresDontTrip(t *testing.T) {
+	t.Parallel()
+
+	cb := NewCircuitBreaker(
+		2, 10*time.Second, 1, 2*time.Second,
+		WithClassifier(classifyUnavailable),
+		WithRetryableRecovery(100*time.Millisecond),
+	)
+
+	unavailableFn := func() (any, error) {
+		return nil, errUnavailable
+	}
+
+	for i := 0; i < 5; i++ {
+		if _, err := cb.Call(unavailableFn); !errors.Is(err, errUnavailable) {
+			t.Fatalf("expected the retryable error to be returned, got %v", err)
+		}
+	}
+
+	if cb.state != Closed || cb.counts.ConsecutiveFailures != 0 {
+		t.Fatalf("expected retryable failures not to count, got %s %+v", cb.state, cb.counts)
+	}
+
+	// Hard failures still trip
+	_, _ = cb.Call(func() (any, error) { return nil, errFailure })
+	_, _ = cb.Call(func() (any, error) { return nil, errFailure })
+
+	if cb.state != Open {
+		t.Fatalf("expected hard failures to trip the circuit, got %s", cb.state)
+	}
+}
+
+func TestCircuitBreaker_RetryableHalfOpenFailureShortensRecovery(t *testing.T) {
+	t.Parallel()
+
+	newHalfOpen := func() (*circuitBreaker, *fakeClock) {
+		clock := newFakeClock()
+		cb := NewCircuitBreaker(
+			1, 10*time.Second, 1, 2*time.Second,
+			WithClock(clock),
+			WithClassifier(classifyUnavailable),
+			WithRetryableRecovery(100*time.Millisecond),
+		)
+		cb.setState(HalfOpen)
+		return cb, clock
+	}
+
+	retryable, clock := newHalfOpen()
+	_, _ = retryable.Call(func() (any, error) { return nil, errUnavailable })
+
+	if retryable.state != Open {
+		t.Fatalf("expected a retryable half-open failure to reopen, got %s", retryable.state)
+	}
+
+	clock.Advance(200 * time.Millisecond)
+	_, _ = retryable.Call(func() (any, error) { return 42, nil })
+
+	if retryable.state != Closed {
+		t.Fatalf("expected the shortened recovery to have passed, got %s", retryable.state)
+	}
+
+	hard, clock := newHalfOpen()
+	_, _ = hard.Call(func() (any, error) { return nil, errFailure })
+
+	clock.Advance(200 * time.Millisecond)
+	if _, err := hard.Call(func() (any, error) { return 42, nil }); !errors.Is(err, ErrCircuitOpen) {
+		t.Fatalf("expected a hard failure to wait out the full recovery, got %v", err)
+	}
+}
+
+func TestCircuitBreaker_RetryableIgnoredWithoutRecovery(t *testing.T) {
+	t.Parallel()
+
+	cb := NewCircuitBreaker(1, 10*time.Second, 1, 2*time.Second, WithClassifier(classifyUnavailable))
+
+	_, _ = cb.Call(func() (any, error) { return nil, errUnavailable })
+
+	if cb.state != Open {
+		t.Fatalf("expected a retryable failure to count without retryable recovery, got %s", cb.state)
+	}
+}
+
+func TestCircuitBreaker_ClassifierWeight(t *testing.T) {
+	t.Parallel()
+
+	cb := NewCircuitBreaker(3, 10*time.Second, 1, 2*time.Second, WithClassifier(func(err error) Classification {
+		return Classification{IsFailure: true, Weight: 1.5}
+	}))
+
+	_, _ = cb.Call(func() (any, error) { return nil, errFailure })
+	if cb.state != Closed {
+		t.Fatalf("expected state closed after one weighted failure, got %s", cb.state)
+	}
+
+	_, _ = cb.Call(func() (any, error) { return nil, errFailure })
+	if cb.state != Open {
+		t.Fatalf("expected two failures weighing 1.5 to trip a threshold of 3, got %s", cb.state)
+	}
+}
+
+func TestCircuitBreaker_IsFailure(t *testing.T) {
+	t.Parallel()
+
+	clock := newFakeClock()
+	cb := NewCircuitBreaker(1, time.Second, 1, time.Second, WithClock(clock), WithIsFailure(func(err error) bool {
+		return !errors.Is(err, errCallerFault)
+	}))
+
+	// Errors that aren't failures are returned without tripping
+	for i := 0; i < 3; i++ {
+		_, err := cb.Call(func() (any, error) {
+			return nil, errCallerFault
+		})
+		if !errors.Is(err, errCallerFault) {
+			t.Fatalf("expected the error to be returned, got %v", err)
+		}
+	}
+	if cb.state != Closed {
+		t.Fatalf("expected state closed, got %s", cb.state)
+	}
+
+	_, _ = cb.Call(func() (any, error) {
+		return nil, errFailure
+	})
+	if cb.state != Open {
+		t.Fatalf("expected a failure to trip the circuit, got %s", cb.state)
+	}
+
+	// Nor do they reopen the half-open circuit
+	clock.Advance(2 * time.Second)
+	_, _ = cb.Call(func() (any, error) { // Moves the circuit to half-open
+		return nil, errCallerFault
+	})
+	_, _ = cb.Call(func() (any, error) {
+		return nil, errCallerFault
+	})
+	if cb.state != Closed {
+		t.Fatalf("expected a non-failure probe to count as a success, got %s", cb.state)
+	}
+}
+
+func TestCircuitBreaker_TimeoutWeightTripsAtOnce(t *testing.T) {
+	t.Parallel()
+
+	cb := NewCircuitBreaker(3, time.Minute, 1, 10*time.Millisecond, WithTimeoutWeight(3))
+
+	_, _ = cb.Call(func() (any, error) {
+		return nil, errFailure
+	})
+	_, _ = cb.Call(func() (any, error) {
+		return nil, errFailure
+	})
+	if cb.State() != Closed {
+		t.Fatalf("expected ordinary failures to weigh 1, got %s", cb.State())
+	}
+	_, _ = cb.Call(func() (any, error) {
+		return 42, nil
+	})
+
+	_, err := cb.Call(func() (any, error) {
+		time.Sleep(time.Second)
+		return 42, nil
+	})
+	if !errors.Is(err, ErrTimeout) {
+		t.Fatalf("expected ErrTimeout, got %v", err)
+	}
+	if got := cb.State(); got != Open {
+		t.Fatalf("expected a single timeout to trip the circuit, got %s", got)
+	}
+}
+
+func TestCircuitBreaker_TimeoutWeightDouble(t *testing.T) {
+	t.Parallel()
+
+	cb := NewCircuitBreaker(3, time.Minute, 1, 10*time.Millisecond, WithTimeoutWeight(2))
+
+	_, _ = cb.Call(func() (any, error) {
+		time.Sleep(time.Second)
+		return 42, nil
+	})
+	if cb.State() != Closed {
+		t.Fatalf("expected a double-weight timeout to stay below the threshold of 3, got %s", cb.State())
+	}
+
+	_, _ = cb.Call(func() (any, error) {
+		return nil, errFailure
+	})
+	if got := cb.State(); got != Open {
+		t.Fatalf("expected a timeout and a failure to reach the threshold, got %s", got)
+	}
+}
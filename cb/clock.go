@@ -0,0 +1,38 @@
+package cb
+
+import "time"
+
+// Clock tells the current time. Swap it out, e.g. for a cbtest.FakeClock, to
+// control time in tests.
+type Clock interface {
+	Now() time.Time
+}
+
+// realClock is a Clock backed by the system time
+type realClock struct{}
+
+// Now returns the current system time
+func (realClock) Now() time.Time {
+	return time.Now()
+}
+
+// TimerClock is a Clock that can also wake a waiter once it has moved on by a
+// duration, letting background work such as StartProbing follow it. Clocks
+// without After are waited on with the system timer.
+type TimerClock interface {
+	Clock
+	After(d time.Duration) <-chan time.Time
+}
+
+// After returns a channel that receives the time once d has passed
+func (realClock) After(d time.Duration) <-chan time.Time {
+	return time.After(d)
+}
+
+// after returns a channel that receives once clock has moved on by d
+func after(clock Clock, d time.Duration) <-chan time.Time {
+	if tc, ok := clock.(TimerClock); ok {
+		return tc.After(d)
+	}
+	return time.After(d)
+}
@@ -0,0 +1,63 @@
+package cb
+
+import (
+	"sync"
+	"testing"
+	"time"
+)
+
+// fakeClock is a Clock that only moves when advanced
+type fakeClock struct {
+	mu  sync.Mutex
+	now time.Time
+}
+
+func newFakeClock() *fakeClock {
+	return &fakeClock{now: time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)}
+}
+
+func (c *fakeClock) Now() time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return c.now
+}
+
+func (c *fakeClock) Advance(d time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.now = c.now.Add(d)
+}
+
+func TestCircuitBreaker_InjectedClock(t *testing.T) {
+	t.Parallel()
+
+	clock := newFakeClock()
+	cb := NewCircuitBreaker(1, 5*time.Second, 1, 2*time.Second, WithClock(clock))
+
+	failFn := func() (any, error) {
+		return nil, errFailure
+	}
+
+	_, _ = cb.Call(failFn)
+
+	if !cb.lastFailureTime.Equal(clock.Now()) {
+		t.Fatalf("expected last failure time %v, got %v", clock.Now(), cb.lastFailureTime)
+	}
+
+	// Recovery time hasn't passed on the injected clock yet
+	_, err := cb.Call(failFn)
+	if err == nil || cb.state != Open {
+		t.Fatalf("expected the circuit to stay open, got state %s and error %v", cb.state, err)
+	}
+
+	clock.Advance(6 * time.Second)
+
+	_, _ = cb.Call(func() (any, error) {
+		return 42, nil
+	})
+	if cb.state != Closed {
+		t.Fatalf("expected a probe to close the circuit after advancing the clock, got %s", cb.state)
+	}
+}
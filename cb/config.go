@@ -0,0 +1,157 @@
+package cb
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+)
+
+// Config holds the core settings of a breaker in a form that can be loaded
+// from a config file. Durations marshal as Go duration strings like "2s".
+type Config struct {
+	Name                string        // Name that identifies the breaker in metrics
+	FailureThreshold    int           // Failures that trip the circuit
+	RecoveryTime        time.Duration // How long the open circuit waits before probing
+	HalfOpenMaxRequests int           // Probes the half-open circuit admits
+	SuccessesToClose    int           // Successful probes that close the circuit, all of them when zero
+	Timeout             time.Duration // How long a request may run, none when zero
+	Verbose             bool          // Whether every call is logged
+}
+
+// configJSON is the JSON form of Config
+type configJSON struct {
+	Name                string `json:"name,omitempty"`
+	FailureThreshold    int    `json:"failure_threshold,omitempty"`
+	RecoveryTime        string `json:"recovery_time,omitempty"`
+	HalfOpenMaxRequests int    `json:"half_open_max_requests,omitempty"`
+	SuccessesToClose    int    `json:"successes_to_close,omitempty"`
+	Timeout             string `json:"timeout,omitempty"`
+	Verbose             bool   `json:"verbose,omitempty"`
+}
+
+// MarshalJSON encodes the config with durations as Go duration strings
+func (c Config) MarshalJSON() ([]byte, error) {
+	return json.Marshal(configJSON{
+		Name:                c.Name,
+		FailureThreshold:    c.FailureThreshold,
+		RecoveryTime:        formatDuration(c.RecoveryTime),
+		HalfOpenMaxRequests: c.HalfOpenMaxRequests,
+		SuccessesToClose:    c.SuccessesToClose,
+		Timeout:             formatDuration(c.Timeout),
+		Verbose:             c.Verbose,
+	})
+}
+
+// UnmarshalJSON decodes a config with durations given as Go duration strings
+func (c *Config) UnmarshalJSON(data []byte) error {
+	var j configJSON
+	if err := json.Unmarshal(data, &j); err != nil {
+		return err
+	}
+
+	recoveryTime, err := parseDuration("recovery_time", j.RecoveryTime)
+	if err != nil {
+		return err
+	}
+	timeout, err := parseDuration("timeout", j.Timeout)
+	if err != nil {
+		return err
+	}
+
+	*c = Config{
+		Name:                j.Name,
+		FailureThreshold:    j.FailureThreshold,
+		RecoveryTime:        recoveryTime,
+		HalfOpenMaxRequests: j.HalfOpenMaxRequests,
+		SuccessesToClose:    j.SuccessesToClose,
+		Timeout:             timeout,
+		Verbose:             j.Verbose,
+	}
+	return nil
+}
+
+// Options returns the options that apply the config, leaving the defaults of
+// New in place for every zero setting
+func (c Config) Options() []Option {
+	var opts []Option
+	if c.Name != "" {
+		opts = append(opts, WithName(c.Name))
+	}
+	if c.FailureThreshold != 0 {
+		opts = append(opts, WithFailureThreshold(c.FailureThreshold))
+	}
+	if c.RecoveryTime != 0 {
+		opts = append(opts, WithRecoveryTime(c.RecoveryTime))
+	}
+	if c.HalfOpenMaxRequests != 0 {
+		opts = append(opts, WithHalfOpenMaxRequests(c.HalfOpenMaxRequests))
+	}
+	if c.SuccessesToClose != 0 {
+		opts = append(opts, WithSuccessesToClose(c.SuccessesToClose))
+	}
+	if c.Timeout != 0 {
+		opts = append(opts, WithTimeout(c.Timeout))
+	}
+	if c.Verbose {
+		opts = append(opts, WithVerbose(true))
+	}
+	return opts
+}
+
+// Config returns the breaker's current core settings
+func (cb *circuitBreaker) Config() Config {
+	cb.mu.Lock()
+	defer cb.mu.Unlock()
+
+	return Config{
+		Name:                cb.name,
+		FailureThreshold:    cb.failureThreshold,
+		RecoveryTime:        cb.recoveryTime,
+		HalfOpenMaxRequests: cb.halfOpenMaxRequests,
+		SuccessesToClose:    cb.successesToClose,
+		Timeout:             cb.timeout,
+		Verbose:             cb.verbose,
+	}
+}
+
+// StateSnapshot is the live state of a breaker, meant for debug endpoints
+type StateSnapshot struct {
+	Name            string    `json:"name"`
+	State           State     `json:"state"`
+	Counts          Counts    `json:"counts"`
+	LastFailureTime time.Time `json:"last_failure_time"`
+}
+
+// Snapshot returns the breaker's live state
+func (cb *circuitBreaker) Snapshot() StateSnapshot {
+	cb.mu.Lock()
+	defer cb.mu.Unlock()
+
+	return StateSnapshot{
+		Name:            cb.name,
+		State:           cb.reportedState(),
+		Counts:          cb.counts,
+		LastFailureTime: cb.lastFailureTime,
+	}
+}
+
+// formatDuration formats d as a Go duration string, empty for zero
+func formatDuration(d time.Duration) string {
+	if d == 0 {
+		return ""
+	}
+	return d.String()
+}
+
+// parseDuration parses the Go duration string s of the named field, zero for
+// an empty string
+func parseDuration(field, s string) (time.Duration, error) {
+	if s == "" {
+		return 0, nil
+	}
+	d, err := time.ParseDuration(s)
+	if err != nil {
+		return 0, fmt.Errorf("invalid %s: %w", field, err)
+	}
+	return d, nil
+}
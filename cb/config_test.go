@@ -0,0 +1,99 @@
+package cb
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestConfig_JSONRoundTrip(t *testing.T) {
+	t.Parallel()
+
+	cfg := Config{
+		Name:                "payments",
+		FailureThreshold:    3,
+		RecoveryTime:        5 * time.Second,
+		HalfOpenMaxRequests: 2,
+		SuccessesToClose:    1,
+		Timeout:             1500 * time.Millisecond,
+	}
+
+	data, err := json.Marshal(cfg)
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	want := `{"name":"payments","failure_threshold":3,"recovery_time":"5s","half_open_max_requests":2,"successes_to_close":1,"timeout":"1.5s"}`
+	if string(data) != want {
+		t.Fatalf("expected %s, got %s", want, data)
+	}
+
+	var decoded Config
+	if err := json.Unmarshal(data, &decoded); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if decoded != cfg {
+		t.Fatalf("expected %+v, got %+v", cfg, decoded)
+	}
+
+	if got := New(decoded.Options()...).Config(); got != cfg {
+		t.Fatalf("expected the breaker to be configured from %+v, got %+v", cfg, got)
+	}
+}
+
+func TestConfig_Fleet(t *testing.T) {
+	t.Parallel()
+
+	doc := `{"users": {"failure_threshold": 2}, "orders": {"recovery_time": "1m", "timeout": "250ms"}}`
+
+	var fleet map[string]Config
+	if err := json.Unmarshal([]byte(doc), &fleet); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+
+	orders := New(fleet["orders"].Options()...).Config()
+	if orders.RecoveryTime != time.Minute || orders.Timeout != 250*time.Millisecond {
+		t.Fatalf("expected the configured durations, got %+v", orders)
+	}
+	if orders.FailureThreshold != defaultFailureThreshold {
+		t.Fatalf("expected unset settings to keep their defaults, got %+v", orders)
+	}
+}
+
+func TestConfig_InvalidDuration(t *testing.T) {
+	t.Parallel()
+
+	var cfg Config
+	err := json.Unmarshal([]byte(`{"timeout": "soon"}`), &cfg)
+	if err == nil || !strings.Contains(err.Error(), "invalid timeout") {
+		t.Fatalf("expected an invalid timeout error, got %v", err)
+	}
+}
+
+func TestCircuitBreaker_SnapshotJSON(t *testing.T) {
+	t.Parallel()
+
+	clock := newFakeClock()
+	cb := NewCircuitBreaker(1, time.Minute, 1, time.Second, WithName("db"), WithClock(clock))
+	_, _ = cb.Call(func() (any, error) {
+		return nil, errFailure
+	})
+
+	data, err := json.Marshal(cb.Snapshot())
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	want := `{"name":"db","state":"open","counts":{"requests":0,"total_successes":0,"total_failures":0,` +
+		`"consecutive_successes":0,"consecutive_failures":0},"last_failure_time":"2024-01-01T00:00:00Z"}`
+	if string(data) != want {
+		t.Fatalf("expected %s, got %s", want, data)
+	}
+
+	var decoded StateSnapshot
+	if err := json.Unmarshal(data, &decoded); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if decoded.State != Open {
+		t.Fatalf("expected the state to decode as open, got %s", decoded.State)
+	}
+}
@@ -0,0 +1,17 @@
+package cb
+
+// Default is the breaker used by the package-level Call, for scripts and apps
+// that only need one. It has New's defaults and is named "default".
+var Default = New(WithName("default"))
+
+// SetDefault replaces Default with cb, e.g. one configured for the app. Like
+// assigning http.DefaultClient, it isn't safe to do while calls are being
+// made, so it belongs in the app's setup.
+func SetDefault(cb *circuitBreaker) {
+	Default = cb
+}
+
+// Call executes fn through Default, see its Call method
+func Call(fn func() (any, error), opts ...CallOption) (any, error) {
+	return Default.Call(fn, opts...)
+}
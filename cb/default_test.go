@@ -0,0 +1,33 @@
+package cb
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+// Swaps the default breaker, so it can't run in parallel
+func TestCall_UsesDefault(t *testing.T) {
+	defer SetDefault(Default)
+
+	if Default.name != "default" || Default.failureThreshold != defaultFailureThreshold {
+		t.Fatalf("expected a default breaker with New's defaults, got %q with threshold %d", Default.name, Default.failureThreshold)
+	}
+
+	configured := NewCircuitBreaker(1, time.Minute, 1, time.Second)
+	SetDefault(configured)
+
+	_, err := Call(func() (any, error) {
+		return nil, errFailure
+	})
+	if !errors.Is(err, errFailure) {
+		t.Fatalf("expected the failure, got %v", err)
+	}
+	if configured.State() != Open {
+		t.Fatalf("expected the package-level call to go through the configured default, got %s", configured.State())
+	}
+
+	if _, err := Call(func() (any, error) { return 42, nil }); !errors.Is(err, ErrCircuitOpen) {
+		t.Fatalf("expected ErrCircuitOpen, got %v", err)
+	}
+}
@@ -0,0 +1,119 @@
+package cb
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Describe returns a deterministic human-readable summary of the breaker's
+// configuration, current state, and state machine
+func (cb *circuitBreaker) Describe() string {
+	cb.mu.Lock()
+	defer cb.mu.Unlock()
+
+	var b strings.Builder
+
+	fmt.Fprintf(&b, "circuit breaker %q\n", cb.name)
+	fmt.Fprintf(&b, "  state: %s\n", cb.reportedState())
+	fmt.Fprintf(&b, "  mode: %s\n", cb.tripMode())
+	switch {
+	case cb.customTrip != nil:
+	case cb.rate != nil:
+		fmt.Fprintf(&b, "  failure rate: %g over %d requests, at least %d\n", cb.rate.threshold, len(cb.rate.outcomes), cb.rate.minimumRequests)
+	default:
+		fmt.Fprintf(&b, "  failure threshold: %g\n", cb.tripScore())
+	}
+	if r := cb.slowRate; r != nil {
+		fmt.Fprintf(&b, "  slow call rate: %g over %d calls slower than %s, at least %d\n", r.threshold, len(r.outcomes), cb.slowCall, r.minimumRequests)
+	}
+	fmt.Fprintf(&b, "  recovery time: %s\n", cb.recoveryTime)
+	if cb.warmup > 0 {
+		fmt.Fprintf(&b, "  warmup: %s, over: %t\n", cb.warmup, !cb.warmingUp())
+	}
+	if cb.recoveryJitter > 0 {
+		fmt.Fprintf(&b, "  recovery jitter: up to %s\n", cb.recoveryJitter)
+	}
+	if bo := cb.backoff; bo != nil {
+		fmt.Fprintf(&b, "  recovery backoff: x%g up to %s, now %s\n", bo.factor, bo.max, cb.backedOff())
+	}
+	fmt.Fprintf(&b, "  half-open max requests: %d\n", cb.halfOpenMaxRequests)
+	if cb.closeAfter() != cb.halfOpenMaxRequests {
+		fmt.Fprintf(&b, "  successes to close: %d\n", cb.closeAfter())
+	}
+	fmt.Fprintf(&b, "  timeout: %s\n", cb.timeout)
+	if t := cb.tightening; t != nil {
+		fmt.Fprintf(&b, "  auto-tightening: after %d fast successes to p99 x %g, now %s\n", t.streak, t.factor, cb.effectiveTimeout())
+	}
+
+	if cb.limiter != nil {
+		fmt.Fprintf(&b, "  rate limit: %g per %s\n", cb.limiter.capacity, cb.limiter.interval)
+	}
+	if cb.fallback != nil {
+		when := "rejections"
+		if cb.fallbackOnFailure {
+			when = "rejections, failures and timeouts"
+		}
+		fmt.Fprintf(&b, "  fallback: on %s\n", when)
+	}
+	if cb.maxFallbackAge > 0 {
+		fmt.Fprintf(&b, "  max fallback age: %s\n", cb.maxFallbackAge)
+	}
+	if cb.singleProbe {
+		fmt.Fprintf(&b, "  single probe: one in flight at a time\n")
+	}
+	if cb.probeInterval > 0 {
+		fmt.Fprintf(&b, "  probe interval: %s\n", cb.probeInterval)
+	}
+	if cb.shouldProbe != nil {
+		fmt.Fprintf(&b, "  probe admission: custom\n")
+	}
+	if cb.halfOpenWindow > 0 {
+		fmt.Fprintf(&b, "  half-open window: %s\n", cb.halfOpenWindow)
+	}
+	if cb.probeTolerance > 0 {
+		fmt.Fprintf(&b, "  probe failure tolerance: %d in a row\n", cb.probeTolerance)
+	}
+	if cb.maxConcurrent > 0 {
+		fmt.Fprintf(&b, "  max concurrent: %d, %d in flight\n", cb.maxConcurrent, cb.inFlight)
+	}
+	if cb.shedding != nil {
+		fmt.Fprintf(&b, "  gradual shedding: admitting %.2f\n", cb.admissionFraction())
+	}
+
+	fmt.Fprintf(&b, "  transitions:\n")
+	fmt.Fprintf(&b, "    %s -> %s: %s\n", Closed, Open, cb.tripCondition())
+	if cb.twoState {
+		fmt.Fprintf(&b, "    %s -> %s: on a successful retry after %s\n", Open, Closed, cb.recoveryTime)
+	} else {
+		fmt.Fprintf(&b, "    %s -> %s: after %s\n", Open, HalfOpen, cb.recoveryTime)
+		fmt.Fprintf(&b, "    %s -> %s: after %d successful probes\n", HalfOpen, Closed, cb.closeAfter())
+		fmt.Fprintf(&b, "    %s -> %s: on a failed probe\n", HalfOpen, Open)
+	}
+
+	return b.String()
+}
+
+// tripMode describes what decides that the circuit trips
+func (cb *circuitBreaker) tripMode() string {
+	if cb.customTrip != nil {
+		return "custom"
+	}
+	if cb.rate != nil {
+		return "failure rate"
+	}
+	if cb.window != nil {
+		return fmt.Sprintf("failures within %s", cb.window.span)
+	}
+	return "consecutive failures"
+}
+
+// tripCondition describes when the closed circuit opens
+func (cb *circuitBreaker) tripCondition() string {
+	if cb.customTrip != nil {
+		return "once the custom trip condition holds"
+	}
+	if r := cb.rate; r != nil {
+		return fmt.Sprintf("once %g of the last %d requests failed", r.threshold, len(r.outcomes))
+	}
+	return fmt.Sprintf("after %g %s", cb.tripScore(), cb.tripMode())
+}
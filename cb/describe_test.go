@@ -0,0 +1,44 @@
+package cb
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestCircuitBreaker_Describe(t *testing.T) {
+	t.Parallel()
+
+	cb := NewCircuitBreaker(
+		3, 5*time.Second, 2, 1500*time.Millisecond,
+		WithName("payments"),
+		WithRequestRateLimit(10, time.Second),
+	)
+
+	out := cb.Describe()
+	for _, want := range []string{
+		`circuit breaker "payments"`,
+		"state: closed",
+		"mode: consecutive failures",
+		"failure threshold: 3",
+		"recovery time: 5s",
+		"half-open max requests: 2",
+		"timeout: 1.5s",
+		"rate limit: 10 per 1s",
+		"closed -> open: after 3 consecutive failures",
+		"open -> half-open: after 5s",
+		"half-open -> closed: after 2 successful probes",
+	} {
+		if !strings.Contains(out, want) {
+			t.Fatalf("expected description to contain %q, got:\n%s", want, out)
+		}
+	}
+
+	if out != cb.Describe() {
+		t.Fatalf("expected the description to be deterministic")
+	}
+
+	if strings.Contains(out, "gradual shedding") {
+		t.Fatalf("expected unset features to be left out, got:\n%s", out)
+	}
+}
@@ -0,0 +1,62 @@
+package cb
+
+// WithDisabled starts the breaker disabled when disabled is set, e.g. to ship
+// it dark behind a feature flag. See Disable.
+func WithDisabled(disabled bool) Option {
+	return func(cb *circuitBreaker) {
+		cb.disabled = disabled
+	}
+}
+
+// Disable turns the breaker into a pass-through: every call runs its function
+// under the timeout, but nothing is rejected, counted, or allowed to change
+// the state, and State reports Disabled. The underlying state is kept for
+// when the breaker is enabled again.
+func (cb *circuitBreaker) Disable() {
+	cb.mu.Lock()
+	defer cb.mu.Unlock()
+
+	cb.disabled = true
+	cb.publishState()
+}
+
+// Enable undoes Disable, resuming in the state the breaker was disabled in
+func (cb *circuitBreaker) Enable() {
+	cb.mu.Lock()
+	defer cb.mu.Unlock()
+
+	cb.disabled = false
+	cb.publishState()
+}
+
+// reportedState returns the state to report to observers, Disabled for a
+// disabled breaker
+func (cb *circuitBreaker) reportedState() State {
+	if cb.disabled {
+		return Disabled
+	}
+	return cb.state
+}
+
+// publishState makes the reported state visible to State and to the open
+// circuit's fast path. It must be called whenever the state, the recovery
+// deadline, or whether the breaker is disabled changes.
+func (cb *circuitBreaker) publishState() {
+	cb.published.Store(int32(cb.reportedState()))
+	cb.publishOpen()
+}
+
+// finishDisabled returns the outcome of a call that ran through a disabled
+// breaker without recording it
+func (cb *circuitBreaker) finishDisabled(c *call, res callResult, ok bool) (any, error) {
+	switch {
+	case !ok:
+		c.outcome = OutcomeTimeout
+		return nil, ErrTimeout
+	case res.err != nil:
+		c.outcome = OutcomeFailure
+	default:
+		c.outcome = OutcomeSuccess
+	}
+	return res.result, res.err
+}
@@ -0,0 +1,79 @@
+package cb
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestCircuitBreaker_Disabled(t *testing.T) {
+	t.Parallel()
+
+	cb := NewCircuitBreaker(1, time.Minute, 1, 10*time.Millisecond, WithDisabled(true))
+
+	if got := cb.State(); got != Disabled {
+		t.Fatalf("expected state disabled, got %s", got)
+	}
+
+	for i := 0; i < 3; i++ {
+		_, err := cb.Call(func() (any, error) {
+			return nil, errFailure
+		})
+		if !errors.Is(err, errFailure) {
+			t.Fatalf("expected every call to pass through, got %v", err)
+		}
+	}
+
+	// The timeout still applies
+	_, err := cb.Call(func() (any, error) {
+		time.Sleep(time.Second)
+		return 42, nil
+	})
+	if !errors.Is(err, ErrTimeout) {
+		t.Fatalf("expected ErrTimeout, got %v", err)
+	}
+
+	cb.Enable()
+	if got := cb.State(); got != Closed {
+		t.Fatalf("expected the failures not to have tripped the circuit, got %s", got)
+	}
+	if got := cb.Stats(); got.Failures != 0 || got.Counts != (Counts{}) {
+		t.Fatalf("expected nothing to be recorded while disabled, got %+v", got)
+	}
+}
+
+func TestCircuitBreaker_DisableKeepsState(t *testing.T) {
+	t.Parallel()
+
+	cb := NewCircuitBreaker(1, time.Minute, 1, time.Second)
+	cb.Trip()
+
+	cb.Disable()
+	result, err := cb.Call(func() (any, error) {
+		return 42, nil
+	})
+	if err != nil || result != 42 {
+		t.Fatalf("expected the open circuit to pass calls through while disabled, got %v, %v", result, err)
+	}
+
+	cb.Enable()
+	if got := cb.State(); got != Open {
+		t.Fatalf("expected the breaker to resume open, got %s", got)
+	}
+}
+
+func TestCircuitBreaker_ReconfigureDisabled(t *testing.T) {
+	t.Parallel()
+
+	cb := NewCircuitBreaker(1, time.Minute, 1, 10*time.Millisecond)
+
+	cb.Reconfigure(WithDisabled(true))
+	if got := cb.State(); got != Disabled {
+		t.Fatalf("expected state disabled after reconfiguring, got %s", got)
+	}
+
+	cb.Reconfigure(WithDisabled(false))
+	if got := cb.State(); got != Closed {
+		t.Fatalf("expected state closed after reconfiguring, got %s", got)
+	}
+}
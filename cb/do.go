@@ -0,0 +1,56 @@
+package cb
+
+import "sync"
+
+// flight is a call made through Do that later callers with the same key wait
+// on instead of making their own
+type flight struct {
+	done   chan struct{} // Closed once the call has returned
+	result any           // Result of the call, set before done is closed
+	err    error         // Error of the call, set before done is closed
+}
+
+// flightGroup tracks the calls in flight through Do. It has its own lock,
+// since the breaker's is released while calls run.
+type flightGroup struct {
+	mu      sync.Mutex         // Guards flights
+	flights map[string]*flight // Calls in flight, by key
+}
+
+// Do executes fn through the breaker like Call, except that concurrent calls
+// with the same key are collapsed into one: while a call for key is in flight,
+// later callers with that key don't call fn and get its result instead. The
+// breaker only sees the call that ran, so ten goroutines asking for the same
+// thing while the circuit is half-open make one probe, not ten.
+//
+// The key is entirely up to the caller and should identify the operation and
+// its arguments, for instance "user:42", so that only calls that would return
+// the same thing share a result. Calls are only collapsed while in flight,
+// nothing is cached once a call returns, and the opts of the callers that
+// waited are ignored.
+func (cb *circuitBreaker) Do(key string, fn func() (any, error), opts ...CallOption) (any, error) {
+	g := &cb.flights
+	g.mu.Lock()
+	if f, ok := g.flights[key]; ok {
+		g.mu.Unlock()
+		<-f.done
+		return f.result, f.err
+	}
+
+	f := &flight{done: make(chan struct{})}
+	if g.flights == nil {
+		g.flights = make(map[string]*flight)
+	}
+	g.flights[key] = f
+	g.mu.Unlock()
+
+	defer func() {
+		g.mu.Lock()
+		delete(g.flights, key)
+		g.mu.Unlock()
+		close(f.done)
+	}()
+
+	f.result, f.err = cb.Call(fn, opts...)
+	return f.result, f.err
+}
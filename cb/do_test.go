@@ -0,0 +1,89 @@
+package cb
+
+import (
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestCircuitBreaker_DoCollapsesHalfOpenProbes(t *testing.T) {
+	t.Parallel()
+
+	cb := NewCircuitBreaker(1, time.Minute, 1, time.Second)
+	cb.setState(HalfOpen)
+
+	var runs atomic.Int32
+	started, release := make(chan struct{}), make(chan struct{})
+	fn := func() (any, error) {
+		if runs.Add(1) == 1 {
+			close(started)
+		}
+		<-release
+		return 42, nil
+	}
+
+	results := make(chan any, 10)
+	errs := make(chan error, 10)
+	do := func() {
+		result, err := cb.Do("user:42", fn)
+		results <- result
+		errs <- err
+	}
+
+	go do()
+	<-started
+
+	var ready sync.WaitGroup
+	for i := 0; i < 9; i++ {
+		ready.Add(1)
+		go func() {
+			ready.Done()
+			do()
+		}()
+	}
+	ready.Wait()
+	time.Sleep(50 * time.Millisecond) // Let the waiters join the flight
+	close(release)
+
+	for i := 0; i < 10; i++ {
+		if err := <-errs; err != nil {
+			t.Fatalf("expected every caller to share the probe's success, got %v", err)
+		}
+		if result := <-results; result != 42 {
+			t.Fatalf("expected result 42, got %v", result)
+		}
+	}
+	if got := runs.Load(); got != 1 {
+		t.Fatalf("expected a single probe, got %d", got)
+	}
+	if cb.State() != Closed {
+		t.Fatalf("expected the probe to close the circuit, got %s", cb.State())
+	}
+}
+
+func TestCircuitBreaker_DoKeysAndSequentialCalls(t *testing.T) {
+	t.Parallel()
+
+	cb := NewCircuitBreaker(5, time.Minute, 1, time.Second)
+	errFailure := errors.New("failure")
+
+	var runs int
+	fn := func() (any, error) {
+		runs++
+		return nil, errFailure
+	}
+
+	for _, key := range []string{"a", "a", "b"} {
+		if _, err := cb.Do(key, fn); !errors.Is(err, errFailure) {
+			t.Fatalf("expected the failure, got %v", err)
+		}
+	}
+	if runs != 3 {
+		t.Fatalf("expected calls that don't overlap to all run, got %d runs", runs)
+	}
+	if got := cb.Stats().Failures; got != 3 {
+		t.Fatalf("expected 3 failures recorded, got %d", got)
+	}
+}
@@ -0,0 +1,90 @@
+package cb
+
+import (
+	"errors"
+	"fmt"
+	"time"
+)
+
+var (
+	// ErrCircuitOpen is returned when the open circuit blocks a request,
+	// wrapped in an OpenError that tells when to retry
+	ErrCircuitOpen = errors.New("circuit open, request blocked")
+
+	// ErrTimeout is returned when a request doesn't finish within the timeout
+	ErrTimeout = errors.New("request timed out")
+
+	// ErrTooManyRequests is wrapped by the errors returned when the breaker
+	// already lets through as many calls as it allows
+	ErrTooManyRequests = errors.New("too many requests")
+
+	// ErrHalfOpenBudgetExceeded is the ErrTooManyRequests returned by the
+	// half-open circuit. Unlike ErrCircuitOpen it means recovery is underway,
+	// so retrying soon is reasonable.
+	ErrHalfOpenBudgetExceeded = fmt.Errorf("half-open probe budget exceeded, %w", ErrTooManyRequests)
+
+	// ErrConcurrencyLimit is the ErrTooManyRequests returned by the closed
+	// circuit when WithMaxConcurrent calls are already running
+	ErrConcurrencyLimit = fmt.Errorf("concurrency limit reached, %w", ErrTooManyRequests)
+
+	// ErrRateLimited is returned when a request exceeds the configured rate limit
+	ErrRateLimited = errors.New("rate limit exceeded, request rejected")
+
+	// ErrRetryBudgetExceeded is returned when a retry would exceed the retry
+	// budget
+	ErrRetryBudgetExceeded = errors.New("retry budget exceeded, retry rejected")
+
+	// ErrLoadShed is returned when gradual shedding turns a request away
+	ErrLoadShed = errors.New("request shed, dependency degraded")
+
+	// ErrResultType is returned by CallT when the result isn't of the
+	// requested type
+	ErrResultType = errors.New("unexpected result type")
+
+	// ErrInvalidConfig is wrapped by the errors Builder.Build returns for
+	// settings that can't work
+	ErrInvalidConfig = errors.New("invalid circuit breaker config")
+
+	// ErrShutdown is returned for every call made after Shutdown
+	ErrShutdown = errors.New("circuit breaker shut down")
+)
+
+// OpenError is the error returned when the open circuit blocks a request. It
+// is ErrCircuitOpen as far as errors.Is is concerned, and tells how long
+// until the circuit will let a probe through.
+type OpenError struct {
+	retryAfter time.Duration // Time left in the recovery period when the request was blocked
+}
+
+func (e *OpenError) Error() string {
+	return ErrCircuitOpen.Error()
+}
+
+// Unwrap returns ErrCircuitOpen
+func (e *OpenError) Unwrap() error {
+	return ErrCircuitOpen
+}
+
+// RetryAfter returns the time left until the circuit recovers, suitable for a
+// Retry-After header. It's an estimate, since other callers may get to probe
+// first and the probes may reopen the circuit.
+func (e *OpenError) RetryAfter() time.Duration {
+	return e.retryAfter
+}
+
+// CallError is the error returned when the function run through the breaker
+// returns one. It records the state the call was made in, and unwraps to the
+// function's error so that errors.Is and errors.As still reach it.
+type CallError struct {
+	State State // State the breaker was in when the call was admitted
+	Err   error // Error returned by the function
+}
+
+func (e *CallError) Error() string {
+	return fmt.Sprintf("circuit breaker (%s): %v", e.State, e.Err)
+}
+
+// Unwrap returns the function's error
+func (e *CallError) Unwrap() error {
+	return e.Err
+}
@@ -0,0 +1,140 @@
+package cb
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+)
+
+func TestCircuitBreaker_RejectionErrorPerState(t *testing.T) {
+	t.Parallel()
+
+	successFn := func() (any, error) {
+		return 42, nil
+	}
+
+	open := NewCircuitBreaker(1, time.Minute, 2, 2*time.Second)
+	open.setState(Open)
+	open.startRecovery()
+
+	_, err := open.Call(successFn)
+	if !errors.Is(err, ErrCircuitOpen) || errors.Is(err, ErrHalfOpenBudgetExceeded) {
+		t.Fatalf("expected ErrCircuitOpen in open state, got %v", err)
+	}
+
+	halfOpen := NewCircuitBreaker(1, time.Minute, 2, 2*time.Second)
+	halfOpen.setState(HalfOpen)
+	halfOpen.counts.Requests = 2 // Both probes already admitted
+
+	_, err = halfOpen.Call(successFn)
+	if !errors.Is(err, ErrHalfOpenBudgetExceeded) || !errors.Is(err, ErrTooManyRequests) || errors.Is(err, ErrCircuitOpen) {
+		t.Fatalf("expected ErrHalfOpenBudgetExceeded in half-open state, got %v", err)
+	}
+
+	if halfOpen.state != HalfOpen {
+		t.Fatalf("expected the rejection to leave the state alone, got %s", halfOpen.state)
+	}
+}
+
+func TestCircuitBreaker_TimeoutError(t *testing.T) {
+	t.Parallel()
+
+	cb := NewCircuitBreaker(1, time.Minute, 1, 10*time.Millisecond)
+
+	_, err := cb.Call(func() (any, error) {
+		time.Sleep(time.Second)
+		return 42, nil
+	})
+	if !errors.Is(err, ErrTimeout) {
+		t.Fatalf("expected ErrTimeout, got %v", err)
+	}
+
+	_, err = cb.Call(func() (any, error) {
+		return 42, nil
+	})
+	if !errors.Is(err, ErrCircuitOpen) {
+		t.Fatalf("expected the timeout to trip the circuit and ErrCircuitOpen to follow, got %v", err)
+	}
+}
+
+func TestCircuitBreaker_OpenErrorRetryAfter(t *testing.T) {
+	t.Parallel()
+
+	clock := newFakeClock()
+	cb := NewCircuitBreaker(1, 10*time.Second, 1, time.Second, WithClock(clock))
+
+	_, _ = cb.Call(func() (any, error) {
+		return nil, errors.New("failure")
+	})
+	clock.Advance(3 * time.Second)
+
+	_, err := cb.Call(func() (any, error) {
+		return 42, nil
+	})
+	var openErr *OpenError
+	if !errors.As(err, &openErr) || !errors.Is(err, ErrCircuitOpen) {
+		t.Fatalf("expected an OpenError wrapping ErrCircuitOpen, got %v", err)
+	}
+	if got := openErr.RetryAfter(); got != 7*time.Second {
+		t.Fatalf("expected retry after 7s, got %s", got)
+	}
+}
+
+// queryError is an error type callers reach with errors.As
+type queryError struct {
+	query string
+}
+
+func (e *queryError) Error() string {
+	return "query failed: " + e.query
+}
+
+func TestCircuitBreaker_CallErrorUnwrapChain(t *testing.T) {
+	t.Parallel()
+
+	cb := NewCircuitBreaker(5, time.Minute, 1, time.Second)
+	cause := fmt.Errorf("fetching user: %w", &queryError{query: "select 1"})
+
+	_, err := cb.Call(func() (any, error) {
+		return nil, cause
+	})
+
+	var callErr *CallError
+	if !errors.As(err, &callErr) || callErr.State != Closed {
+		t.Fatalf("expected a CallError recording the closed state, got %v", err)
+	}
+	if errors.Unwrap(err) != cause {
+		t.Fatalf("expected the CallError to unwrap to the function's error, got %v", errors.Unwrap(err))
+	}
+
+	var qe *queryError
+	if !errors.As(err, &qe) || qe.query != "select 1" {
+		t.Fatalf("expected errors.As to reach the original error type, got %v", err)
+	}
+	if want := "circuit breaker (closed): fetching user: query failed: select 1"; err.Error() != want {
+		t.Fatalf("expected %q, got %q", want, err.Error())
+	}
+}
+
+func TestCircuitBreaker_CallErrorOnlyWrapsFunctionErrors(t *testing.T) {
+	t.Parallel()
+
+	cb := NewCircuitBreaker(1, time.Minute, 1, 10*time.Millisecond)
+
+	_, err := cb.Call(func() (any, error) {
+		time.Sleep(50 * time.Millisecond)
+		return 42, nil
+	})
+	var callErr *CallError
+	if errors.As(err, &callErr) || !errors.Is(err, ErrTimeout) {
+		t.Fatalf("expected a bare ErrTimeout, got %v", err)
+	}
+
+	_, err = cb.Call(func() (any, error) {
+		return 42, nil
+	})
+	if errors.As(err, &callErr) || !errors.Is(err, ErrCircuitOpen) {
+		t.Fatalf("expected a rejection that isn't a CallError, got %v", err)
+	}
+}
@@ -0,0 +1,145 @@
+package cb
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// exportVersion is the version of the format written by Export
+const exportVersion = 1
+
+// exportedState is the runtime state of a breaker as written by Export
+type exportedState struct {
+	Version           int            `json:"version"`
+	State             State          `json:"state"`
+	Counts            Counts         `json:"counts"`
+	FailureScore      float64        `json:"failure_score"`
+	LastFailureTime   time.Time      `json:"last_failure_time"`
+	LastFailureReason FailureReason  `json:"last_failure_reason,omitempty"`
+	OpenUntil         time.Time      `json:"open_until"`
+	HalfOpenSince     time.Time      `json:"half_open_since"`
+	Reopens           int            `json:"reopens"`
+	Generation        uint64         `json:"generation"`
+	Totals            exportedTotals `json:"totals"`
+}
+
+// exportedTotals are the cumulative counters as written by Export
+type exportedTotals struct {
+	Requests   int `json:"requests"`
+	Successes  int `json:"successes"`
+	Failures   int `json:"failures"`
+	Rejections int `json:"rejections"`
+	Timeouts   int `json:"timeouts"`
+	Throttled  int `json:"throttled"`
+	Canceled   int `json:"canceled"`
+	Slow       int `json:"slow"`
+}
+
+// Export serializes the breaker's runtime state, its state, counters,
+// timestamps and generation, for a standby to Import at failover. Unlike
+// Config it carries no settings, which each side configures for itself, and
+// the samples of a sliding window or failure rate aren't carried over.
+func (cb *circuitBreaker) Export() []byte {
+	cb.mu.Lock()
+	defer cb.mu.Unlock()
+
+	cb.foldRejections()
+	data, _ := json.Marshal(exportedState{
+		Version:           exportVersion,
+		State:             cb.state,
+		Counts:            cb.counts,
+		FailureScore:      cb.failureScore,
+		LastFailureTime:   cb.lastFailureTime,
+		LastFailureReason: cb.lastFailureReason,
+		OpenUntil:         cb.openUntil,
+		HalfOpenSince:     cb.halfOpenSince,
+		Reopens:           cb.reopens,
+		Generation:        cb.generation,
+		Totals: exportedTotals{
+			Requests:   cb.totals.requests,
+			Successes:  cb.totals.successes,
+			Failures:   cb.totals.failures,
+			Rejections: cb.totals.rejections,
+			Timeouts:   cb.totals.timeouts,
+			Throttled:  cb.totals.throttled,
+			Canceled:   cb.totals.canceled,
+			Slow:       cb.totals.slow,
+		},
+	})
+	return data
+}
+
+// Import restores runtime state written by Export, replacing the breaker's
+// own in one step. The state is validated first, and left untouched if it's
+// inconsistent, e.g. open without a recovery deadline. Calls in flight when
+// it's imported no longer count toward any decision, and state change hooks
+// fire if the state changes.
+func (cb *circuitBreaker) Import(data []byte) error {
+	var s exportedState
+	if err := json.Unmarshal(data, &s); err != nil {
+		return fmt.Errorf("decoding breaker state: %w", err)
+	}
+	if err := s.validate(); err != nil {
+		return fmt.Errorf("invalid breaker state: %w", err)
+	}
+
+	cb.mu.Lock()
+	defer cb.unlock()
+
+	if cb.state != s.State {
+		cb.transition(s.State)
+	}
+	cb.counts = s.Counts
+	cb.failureScore = s.FailureScore
+	cb.lastFailureTime = s.LastFailureTime
+	cb.lastFailureReason = s.LastFailureReason
+	cb.openUntil = s.OpenUntil
+	cb.halfOpenSince = s.HalfOpenSince
+	cb.reopens = s.Reopens
+	cb.generation = max(cb.generation, s.Generation) + 1
+	cb.publishOpen()
+	cb.fastRejections.Store(0) // Replaced by the imported totals
+	cb.totals = totals{
+		requests:   s.Totals.Requests,
+		successes:  s.Totals.Successes,
+		failures:   s.Totals.Failures,
+		rejections: s.Totals.Rejections,
+		timeouts:   s.Totals.Timeouts,
+		throttled:  s.Totals.Throttled,
+		canceled:   s.Totals.Canceled,
+		slow:       s.Totals.Slow,
+	}
+	cb.log().Info("Circuit state imported", "state", s.State)
+	return nil
+}
+
+// validate checks that the exported state is one a breaker could be in
+func (s *exportedState) validate() error {
+	if s.Version != exportVersion {
+		return fmt.Errorf("unsupported version %d", s.Version)
+	}
+
+	switch s.State {
+	case Closed, HalfOpen:
+	case Open:
+		if s.OpenUntil.IsZero() || s.OpenUntil.Before(s.LastFailureTime) {
+			return errors.New("open without a recovery deadline after its last failure")
+		}
+	default:
+		return fmt.Errorf("state %s can't be imported", s.State)
+	}
+
+	c := s.Counts
+	if c.Requests < 0 || c.TotalSuccesses < 0 || c.TotalFailures < 0 || c.ConsecutiveSuccesses < 0 || c.ConsecutiveFailures < 0 {
+		return errors.New("negative counts")
+	}
+	if c.ConsecutiveSuccesses > c.TotalSuccesses || c.ConsecutiveFailures > c.TotalFailures {
+		return errors.New("more consecutive outcomes than total ones")
+	}
+	if s.Reopens < 0 || s.FailureScore < 0 {
+		return errors.New("negative reopens or failure score")
+	}
+	return nil
+}
@@ -0,0 +1,94 @@
+package cb
+
+import (
+	"encoding/json"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestCircuitBreaker_ExportImport(t *testing.T) {
+	t.Parallel()
+
+	clock := newFakeClock()
+	active := NewCircuitBreaker(2, time.Minute, 1, time.Second, WithClock(clock))
+	for i := 0; i < 2; i++ {
+		_, _ = active.Call(func() (any, error) {
+			return nil, errFailure
+		})
+	}
+	clock.Advance(20 * time.Second)
+
+	var changes []State
+	standby := NewCircuitBreaker(2, time.Minute, 1, time.Second, WithClock(clock), WithOnStateChange(func(_, to State) {
+		changes = append(changes, to)
+	}))
+	if err := standby.Import(active.Export()); err != nil {
+		t.Fatalf("expected the state to import, got %v", err)
+	}
+
+	if len(changes) != 1 || changes[0] != Open {
+		t.Fatalf("expected the import to open the standby, got %v", changes)
+	}
+	want, got := active.Stats(), standby.Stats()
+	if got.State != Open || !got.OpenUntil.Equal(want.OpenUntil) || got.Failures != 2 || got.Requests != 2 {
+		t.Fatalf("expected the standby to inherit %+v, got %+v", want, got)
+	}
+
+	// The standby keeps waiting out the active's recovery time
+	_, err := standby.Call(func() (any, error) {
+		return 42, nil
+	})
+	var openErr *OpenError
+	if !errors.As(err, &openErr) || openErr.RetryAfter() != 40*time.Second {
+		t.Fatalf("expected to retry in 40s, got %v", err)
+	}
+}
+
+func TestCircuitBreaker_ImportRejectsInconsistentState(t *testing.T) {
+	t.Parallel()
+
+	cb := NewCircuitBreaker(2, time.Minute, 1, time.Second)
+	tests := map[string]func(*exportedState){
+		"open without deadline": func(s *exportedState) {
+			s.State = Open
+		},
+		"deadline before failure": func(s *exportedState) {
+			s.State = Open
+			s.LastFailureTime = time.Now()
+			s.OpenUntil = s.LastFailureTime.Add(-time.Second)
+		},
+		"disabled": func(s *exportedState) {
+			s.State = Disabled
+		},
+		"negative counts": func(s *exportedState) {
+			s.Counts.Requests = -1
+		},
+		"too many consecutive": func(s *exportedState) {
+			s.Counts.ConsecutiveFailures = 3
+			s.Counts.TotalFailures = 1
+		},
+		"unknown version": func(s *exportedState) {
+			s.Version = 2
+		},
+	}
+
+	for name, corrupt := range tests {
+		var s exportedState
+		if err := json.Unmarshal(cb.Export(), &s); err != nil {
+			t.Fatalf("expected the export to decode, got %v", err)
+		}
+		corrupt(&s)
+		data, _ := json.Marshal(s)
+
+		if err := cb.Import(data); err == nil {
+			t.Fatalf("%s: expected the import to fail", name)
+		}
+	}
+	if err := cb.Import([]byte("{")); err == nil {
+		t.Fatalf("expected malformed data to fail")
+	}
+	if cb.State() != Closed || cb.Stats().Requests != 0 {
+		t.Fatalf("expected failed imports to leave the breaker untouched, got %+v", cb.Stats())
+	}
+}
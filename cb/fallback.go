@@ -0,0 +1,108 @@
+package cb
+
+import (
+	"errors"
+	"time"
+)
+
+// FallbackResult is how a call handed to the fallback was settled, as
+// reported under the "result" label of MetricFallbacks
+type FallbackResult string
+
+const (
+	FallbackServed FallbackResult = "served" // The fallback produced a result
+	FallbackFailed FallbackResult = "failed" // The fallback returned an error
+	FallbackStale  FallbackResult = "stale"  // The cached result was too old to serve
+)
+
+// WithFallback registers fallback to serve calls the open circuit rejects, so
+// that callers transparently get a cached or default response instead of
+// ErrCircuitOpen or ErrHalfOpenBudgetExceeded. It's passed the rejection
+// error, and its result is returned as if the protected function had
+// produced it. It runs on the calling goroutine after the breaker's lock is
+// released.
+func WithFallback(fallback func(err error) (any, error)) Option {
+	return func(cb *circuitBreaker) {
+		cb.fallback = func(c *call, err error) (any, error) {
+			result, err := fallback(err)
+			c.emitFallback(fallbackResult(err))
+			return result, err
+		}
+	}
+}
+
+// WithCachedFallback registers cached as the fallback, like WithFallback, for
+// a source that also knows how old its result is. Results older than the
+// WithMaxFallbackAge limit are refused, in which case the call returns its
+// original error rather than dangerously stale data.
+func WithCachedFallback(cached func(err error) (result any, age time.Duration, cacheErr error)) Option {
+	return func(cb *circuitBreaker) {
+		cb.fallback = func(c *call, err error) (any, error) {
+			return c.serveCached(cached, err)
+		}
+	}
+}
+
+// WithMaxFallbackAge sets how old a result WithCachedFallback may serve. Zero,
+// the default, serves results of any age.
+func WithMaxFallbackAge(age time.Duration) Option {
+	return func(cb *circuitBreaker) {
+		cb.maxFallbackAge = age
+	}
+}
+
+// serveCached runs the cached fallback for a call that ended with err,
+// refusing a result past the maximum age
+func (c *call) serveCached(cached func(error) (any, time.Duration, error), err error) (any, error) {
+	result, age, cacheErr := cached(err)
+	if cacheErr == nil && c.maxFallbackAge > 0 && age > c.maxFallbackAge {
+		c.log.Warn("Cached fallback too stale, not serving it", "age", age, "maxAge", c.maxFallbackAge)
+		c.emitFallback(FallbackStale)
+		return nil, err
+	}
+
+	c.emitFallback(fallbackResult(cacheErr))
+	return result, cacheErr
+}
+
+// fallbackResult returns how a fallback that returned err settled the call
+func fallbackResult(err error) FallbackResult {
+	if err != nil {
+		return FallbackFailed
+	}
+	return FallbackServed
+}
+
+// WithFallbackOnFailure also hands calls that time out or fail to the
+// fallback registered with WithFallback, passing it the call's error. Errors
+// the classifier doesn't deem failures are still returned as is.
+func WithFallbackOnFailure(enabled bool) Option {
+	return func(cb *circuitBreaker) {
+		cb.fallbackOnFailure = enabled
+	}
+}
+
+// fallbackFor returns the fallback that should serve a call that ended with
+// err, or nil if the call's own result stands. The fallback runs after the
+// lock is released, so what it needs from the breaker is taken into c here.
+func (cb *circuitBreaker) fallbackFor(c *call, err error) func(*call, error) (any, error) {
+	if cb.fallback == nil || err == nil {
+		return nil
+	}
+
+	switch c.outcome {
+	case OutcomeRejected:
+		if !errors.Is(err, ErrCircuitOpen) && !errors.Is(err, ErrHalfOpenBudgetExceeded) {
+			return nil
+		}
+	case OutcomeFailure, OutcomeTimeout:
+		if !cb.fallbackOnFailure {
+			return nil
+		}
+	default:
+		return nil
+	}
+
+	c.sink, c.breaker, c.maxFallbackAge = cb.sink, cb.name, cb.maxFallbackAge
+	return cb.fallback
+}
@@ -0,0 +1,160 @@
+package cb
+
+import (
+	"errors"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+)
+
+var errNotFound = errors.New("not found")
+
+func TestCircuitBreaker_FallbackWhenOpen(t *testing.T) {
+	t.Parallel()
+
+	var got error
+	cb := NewCircuitBreaker(1, time.Minute, 1, time.Second, WithFallback(func(err error) (any, error) {
+		got = err
+		return "cached", nil
+	}))
+
+	// The failure itself isn't handed to the fallback without opting in
+	_, err := cb.Call(func() (any, error) {
+		return nil, errFailure
+	})
+	if !errors.Is(err, errFailure) {
+		t.Fatalf("expected the failure to be returned as is, got %v", err)
+	}
+
+	result, err := cb.Call(func() (any, error) {
+		return 42, nil
+	})
+	if err != nil || result != "cached" {
+		t.Fatalf("expected the fallback's result, got %v, %v", result, err)
+	}
+	if !errors.Is(got, ErrCircuitOpen) {
+		t.Fatalf("expected the fallback to get ErrCircuitOpen, got %v", got)
+	}
+}
+
+func TestCircuitBreaker_FallbackWhenNotPickedAsProbe(t *testing.T) {
+	t.Parallel()
+
+	cb := NewCircuitBreaker(1, time.Minute, 1, time.Second,
+		WithShouldProbe(func() bool { return false }),
+		WithFallback(func(error) (any, error) {
+			return "cached", nil
+		}),
+	)
+	cb.setState(HalfOpen)
+
+	result, err := cb.Call(func() (any, error) {
+		return 42, nil
+	})
+	if err != nil || result != "cached" {
+		t.Fatalf("expected the fallback's result, got %v, %v", result, err)
+	}
+}
+
+func TestCircuitBreaker_FallbackOnFailure(t *testing.T) {
+	t.Parallel()
+
+	var got []error
+	cb := NewCircuitBreaker(5, time.Minute, 1, 20*time.Millisecond,
+		WithFallback(func(err error) (any, error) {
+			got = append(got, err)
+			return "default", nil
+		}),
+		WithFallbackOnFailure(true),
+		WithClassifier(func(err error) Classification {
+			return Classification{IsFailure: !errors.Is(err, errNotFound), Weight: 1}
+		}),
+	)
+
+	result, err := cb.Call(func() (any, error) {
+		return nil, errFailure
+	})
+	if err != nil || result != "default" {
+		t.Fatalf("expected the fallback to serve a failure, got %v, %v", result, err)
+	}
+
+	result, err = cb.Call(func() (any, error) {
+		time.Sleep(time.Second)
+		return 42, nil
+	})
+	if err != nil || result != "default" {
+		t.Fatalf("expected the fallback to serve a timeout, got %v, %v", result, err)
+	}
+
+	// Errors that aren't failures bypass the fallback
+	_, err = cb.Call(func() (any, error) {
+		return nil, errNotFound
+	})
+	if !errors.Is(err, errNotFound) {
+		t.Fatalf("expected a non-failure error to be returned as is, got %v", err)
+	}
+
+	if len(got) != 2 || !errors.Is(got[0], errFailure) {
+		t.Fatalf("expected the fallback to see the failure and the timeout, got %v", got)
+	}
+}
+
+func TestCircuitBreaker_FallbackDescribe(t *testing.T) {
+	t.Parallel()
+
+	cb := NewCircuitBreaker(1, time.Minute, 1, time.Second, WithFallback(func(err error) (any, error) {
+		return nil, err
+	}))
+
+	if out := cb.Describe(); !strings.Contains(out, "fallback: on rejections\n") {
+		t.Fatalf("expected the fallback in the description, got:\n%s", out)
+	}
+}
+
+func TestCircuitBreaker_MaxFallbackAge(t *testing.T) {
+	t.Parallel()
+
+	age := time.Minute
+	sink := &spySink{}
+	cb := NewCircuitBreaker(1, time.Hour, 1, time.Second,
+		WithName("db"),
+		WithMetricsSink(sink),
+		WithMaxFallbackAge(5*time.Minute),
+		WithCachedFallback(func(error) (any, time.Duration, error) {
+			return "cached", age, nil
+		}),
+	)
+	_, _ = cb.Call(func() (any, error) {
+		return nil, errFailure
+	})
+
+	result, err := cb.Call(func() (any, error) {
+		return 42, nil
+	})
+	if err != nil || result != "cached" {
+		t.Fatalf("expected a fresh cached result to be served, got %v, %v", result, err)
+	}
+
+	age = 10 * time.Minute
+	result, err = cb.Call(func() (any, error) {
+		return 42, nil
+	})
+	if !errors.Is(err, ErrCircuitOpen) || result != nil {
+		t.Fatalf("expected a stale cached result to be refused, got %v, %v", result, err)
+	}
+
+	var fallbacks []string
+	for _, s := range sink.series {
+		if strings.Contains(s, MetricFallbacks) {
+			fallbacks = append(fallbacks, s)
+		}
+	}
+	want := []string{
+		"incr circuit_breaker_fallbacks{breaker=db,result=served}",
+		"incr circuit_breaker_fallbacks{breaker=db,result=stale}",
+	}
+	if !slices.Equal(fallbacks, want) {
+		t.Fatalf("expected fallback series %v, got %v", want, fallbacks)
+	}
+}
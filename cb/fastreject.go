@@ -0,0 +1,61 @@
+package cb
+
+import (
+	"log/slog"
+	"time"
+)
+
+// openSnapshot is what it takes to reject a call made while the circuit is
+// open without taking the lock
+type openSnapshot struct {
+	until time.Time    // When the open circuit is due to probe
+	clock Clock        // Clock the deadline is checked against
+	log   *slog.Logger // Logger for the rejections
+}
+
+// publishOpen lets calls made while the circuit is open be rejected without
+// the lock, as long as rejecting them involves nothing but counting them. It
+// must be called whenever the state, the recovery deadline or the
+// configuration changes.
+func (cb *circuitBreaker) publishOpen() {
+	if cb.reportedState() != Open || !cb.rejectsQuietly() {
+		cb.fastOpen.Store(nil)
+		return
+	}
+	cb.fastOpen.Store(&openSnapshot{until: cb.openUntil, clock: cb.clock, log: cb.log()})
+}
+
+// rejectsQuietly reports whether a rejection by the open circuit has no
+// observer to notify and no state to update beyond the totals
+func (cb *circuitBreaker) rejectsQuietly() bool {
+	return cb.onReject == nil && cb.onCallComplete == nil && cb.fallback == nil && cb.tracer == nil &&
+		!cb.emitting() && !cb.verbose && cb.idleReset <= 0 && cb.retryBudget == nil
+}
+
+// rejectFast rejects c without the lock if the circuit is open and not yet
+// due to probe, returning nil when the call has to go through dispatch
+func (cb *circuitBreaker) rejectFast(c *call, logArgs []any) *OpenError {
+	open := cb.fastOpen.Load()
+	if open == nil || c.op != "" || c.retry || cb.isShutdown() {
+		return nil
+	}
+	retryAfter := open.until.Sub(open.clock.Now())
+	if retryAfter <= 0 {
+		return nil
+	}
+
+	cb.fastRejections.Add(1)
+	c.madeIn = Open
+	if open.log.Enabled(c.ctx, slog.LevelDebug) {
+		open.log.With(logArgs...).Debug("Circuit is still open, blocking request", "retryAfter", retryAfter)
+	}
+	return &OpenError{retryAfter: retryAfter}
+}
+
+// foldRejections adds the rejections made without the lock to the totals. It
+// must be called with the lock held before the totals are read or replaced.
+func (cb *circuitBreaker) foldRejections() {
+	n := int(cb.fastRejections.Swap(0))
+	cb.totals.requests += n
+	cb.totals.rejections += n
+}
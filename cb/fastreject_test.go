@@ -0,0 +1,84 @@
+package cb
+
+import (
+	"errors"
+	"log/slog"
+	"testing"
+	"time"
+)
+
+func TestCircuitBreaker_OpenRejectsWithoutLock(t *testing.T) {
+	t.Parallel()
+
+	cb := NewCircuitBreaker(1, time.Minute, 1, time.Second)
+	cb.Trip()
+
+	// A rejection mustn't wait for the lock, held here for the whole call
+	cb.mu.Lock()
+	done := make(chan error, 1)
+	go func() {
+		_, err := cb.Call(func() (any, error) {
+			return 42, nil
+		})
+		done <- err
+	}()
+	select {
+	case err := <-done:
+		var openErr *OpenError
+		if !errors.As(err, &openErr) || openErr.RetryAfter() <= 0 {
+			t.Fatalf("expected an OpenError with a retry delay, got %v", err)
+		}
+	case <-time.After(time.Second):
+		t.Fatalf("expected the open circuit to reject without the lock")
+	}
+	cb.mu.Unlock()
+
+	if got := cb.Stats(); got.Requests != 1 || got.Rejections != 1 {
+		t.Fatalf("expected the rejection in the totals, got %d requests and %d rejections", got.Requests, got.Rejections)
+	}
+}
+
+func TestCircuitBreaker_OpenRejectsObserved(t *testing.T) {
+	t.Parallel()
+
+	cb := NewCircuitBreaker(1, time.Minute, 1, time.Second)
+	cb.Trip()
+
+	rejected := 0
+	cb.Reconfigure(WithOnReject(func() { rejected++ }))
+
+	_, err := cb.Call(func() (any, error) {
+		return 42, nil
+	})
+	if !errors.Is(err, ErrCircuitOpen) {
+		t.Fatalf("expected ErrCircuitOpen, got %v", err)
+	}
+	if rejected != 1 {
+		t.Fatalf("expected the hook added by Reconfigure to see the rejection, got %d", rejected)
+	}
+}
+
+func TestCircuitBreaker_OpenRejectionsLogAtDebug(t *testing.T) {
+	t.Parallel()
+
+	h := newRecordingHandler()
+	cb := NewCircuitBreaker(1, time.Minute, 1, time.Second, WithLogger(slog.New(h)))
+	cb.Trip()
+	before := len(*h.records)
+
+	for i := 0; i < 3; i++ {
+		_, _ = cb.Call(func() (any, error) {
+			return 42, nil
+		})
+	}
+
+	records := (*h.records)[before:]
+	if len(records) != 3 {
+		t.Fatalf("expected a record per rejection, got %d", len(records))
+	}
+	for _, r := range records {
+		if r.Level != slog.LevelDebug {
+			t.Fatalf("expected rejections to log at debug, got %s %q", r.Level, r.Message)
+		}
+	}
+}
@@ -0,0 +1,57 @@
+package cb
+
+import (
+	"context"
+	"fmt"
+)
+
+// CallT executes fn through cb like Call, but returns its result as a T so
+// callers don't have to type-assert it. If the result isn't a T, CallT
+// returns the zero value and an error wrapping ErrResultType.
+func CallT[T any](cb *circuitBreaker, fn func() (T, error), opts ...CallOption) (T, error) {
+	return resultAs[T](cb.Call(func() (any, error) {
+		return fn()
+	}, opts...))
+}
+
+// Breaker is a circuit breaker guarding calls that all return a T. It runs the
+// same state machine as the breaker New returns, whose other methods it has,
+// but its calls take and return a T, so there's no any to assert end to end.
+type Breaker[T any] struct {
+	*circuitBreaker
+}
+
+// NewBreaker initializes a Breaker configured by opts, with the same defaults
+// as New
+func NewBreaker[T any](opts ...Option) *Breaker[T] {
+	return &Breaker[T]{New(opts...)}
+}
+
+// Call executes fn through the breaker, returning the zero T along with the
+// error whenever the call fails or is rejected. Only a fallback can make the
+// result something other than a T, in which case the error wraps
+// ErrResultType.
+func (b *Breaker[T]) Call(fn func() (T, error), opts ...CallOption) (T, error) {
+	return CallT(b.circuitBreaker, fn, opts...)
+}
+
+// CallContext executes fn through the breaker like the untyped CallContext
+func (b *Breaker[T]) CallContext(ctx context.Context, fn func(context.Context) (T, error), opts ...CallOption) (T, error) {
+	return resultAs[T](b.circuitBreaker.CallContext(ctx, func(ctx context.Context) (any, error) {
+		return fn(ctx)
+	}, opts...))
+}
+
+// resultAs returns result as a T, or the zero value and an error wrapping
+// ErrResultType if it isn't one
+func resultAs[T any](result any, err error) (T, error) {
+	var zero T
+	if result == nil {
+		return zero, err
+	}
+	val, ok := result.(T)
+	if !ok {
+		return zero, fmt.Errorf("%w: got %T, want %T", ErrResultType, result, zero)
+	}
+	return val, err
+}
@@ -0,0 +1,107 @@
+package cb
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestCallT_ReturnsTypedResult(t *testing.T) {
+	t.Parallel()
+
+	cb := NewCircuitBreaker(1, time.Second, 1, 2*time.Second)
+
+	val, err := CallT(cb, func() (int, error) {
+		return 42, nil
+	})
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if val != 42 {
+		t.Fatalf("expected 42, got %d", val)
+	}
+}
+
+func TestCallT_ZeroValueOnRejection(t *testing.T) {
+	t.Parallel()
+
+	cb := NewCircuitBreaker(1, time.Minute, 1, 2*time.Second)
+	cb.setState(Open)
+	cb.startRecovery()
+
+	val, err := CallT(cb, func() (string, error) {
+		return "unreachable", nil
+	})
+	if !errors.Is(err, ErrCircuitOpen) {
+		t.Fatalf("expected ErrCircuitOpen, got %v", err)
+	}
+	if val != "" {
+		t.Fatalf("expected the zero value, got %q", val)
+	}
+}
+
+func TestCallT_NilInterfaceResult(t *testing.T) {
+	t.Parallel()
+
+	cb := NewCircuitBreaker(1, time.Second, 1, 2*time.Second)
+
+	val, err := CallT(cb, func() (error, error) {
+		return nil, nil
+	})
+	if err != nil {
+		t.Fatalf("expected a nil interface result to pass through, got %v", err)
+	}
+	if val != nil {
+		t.Fatalf("expected a nil result, got %v", val)
+	}
+}
+
+func TestBreaker_Call(t *testing.T) {
+	t.Parallel()
+
+	type user struct {
+		name string
+	}
+	b := NewBreaker[*user](WithFailureThreshold(1), WithRecoveryTime(time.Minute))
+
+	u, err := b.Call(func() (*user, error) {
+		return &user{name: "ada"}, nil
+	})
+	if err != nil || u.name != "ada" {
+		t.Fatalf("expected user ada, got %v, %v", u, err)
+	}
+
+	u, err = b.Call(func() (*user, error) {
+		return &user{name: "partial"}, errFailure
+	})
+	if !errors.Is(err, errFailure) || u != nil {
+		t.Fatalf("expected the zero value with the failure, got %v, %v", u, err)
+	}
+	if b.State() != Open {
+		t.Fatalf("expected state open, got %s", b.State())
+	}
+
+	u, err = b.CallContext(context.Background(), func(context.Context) (*user, error) {
+		return &user{name: "unreachable"}, nil
+	})
+	if !errors.Is(err, ErrCircuitOpen) || u != nil {
+		t.Fatalf("expected the zero value with ErrCircuitOpen, got %v, %v", u, err)
+	}
+}
+
+func TestBreaker_FallbackOfTheWrongType(t *testing.T) {
+	t.Parallel()
+
+	b := NewBreaker[int](WithFailureThreshold(1), WithFallback(func(error) (any, error) {
+		return "cached", nil
+	}))
+	b.Trip()
+
+	val, err := b.Call(func() (int, error) {
+		return 42, nil
+	})
+	if !errors.Is(err, ErrResultType) || val != 0 {
+		t.Fatalf("expected ErrResultType and the zero value, got %d, %v", val, err)
+	}
+}
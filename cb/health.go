@@ -0,0 +1,111 @@
+package cb
+
+import (
+	"fmt"
+	"time"
+)
+
+// WithHealthCheck sets the lightweight check that StartProbing runs in the
+// background to find out whether the dependency has recovered
+func WithHealthCheck(check func() error) Option {
+	return func(cb *circuitBreaker) {
+		cb.healthCheck = check
+	}
+}
+
+// prober is the background goroutine started by StartProbing
+type prober struct {
+	stop chan struct{} // Closed to ask the goroutine to exit
+	done chan struct{} // Closed once the goroutine has exited
+}
+
+// StartProbing starts a goroutine that checks every interval whether the
+// circuit is open and past its recovery time, and if so runs the health check
+// instead of waiting for a user request to probe. A passing check closes the
+// circuit, while a failing one restarts the recovery timer. It's a no-op
+// without a health check, when already probing, or after Shutdown. Call
+// StopProbing to end it. It returns an error wrapping ErrInvalidConfig, and
+// starts nothing, for an interval of zero or less. The interval is measured
+// with the breaker's clock when it's a TimerClock, such as cbtest.FakeClock.
+func (cb *circuitBreaker) StartProbing(interval time.Duration) error {
+	if interval <= 0 {
+		return fmt.Errorf("%w: probe interval must be positive, got %v", ErrInvalidConfig, interval)
+	}
+
+	cb.mu.Lock()
+	defer cb.mu.Unlock()
+
+	if cb.healthCheck == nil || cb.prober != nil || cb.isShutdown() {
+		return nil
+	}
+
+	p := &prober{stop: make(chan struct{}), done: make(chan struct{})}
+	cb.prober = p
+	go cb.probe(p, cb.clock, interval)
+	return nil
+}
+
+// StopProbing stops the goroutine started by StartProbing, waiting for a
+// health check in progress to finish
+func (cb *circuitBreaker) StopProbing() {
+	cb.mu.Lock()
+	p := cb.prober
+	cb.prober = nil
+	cb.mu.Unlock()
+
+	if p == nil {
+		return
+	}
+	close(p.stop)
+	<-p.done
+}
+
+// probe runs the health check every interval, as told by clock, until p is
+// stopped
+func (cb *circuitBreaker) probe(p *prober, clock Clock, interval time.Duration) {
+	defer close(p.done)
+
+	for {
+		select {
+		case <-p.stop:
+			return
+		case <-after(clock, interval):
+			cb.checkHealth()
+		}
+	}
+}
+
+// checkHealth runs the health check if the open circuit is due to probe,
+// closing or keeping it open depending on the result
+func (cb *circuitBreaker) checkHealth() {
+	cb.mu.Lock()
+	due := cb.state == Open && !cb.disabled && cb.clock.Now().After(cb.openUntil)
+	generation := cb.generation
+	check := cb.healthCheck // Reconfigure may swap it while it runs
+	cb.mu.Unlock()
+
+	if !due || check == nil {
+		return
+	}
+	err := check()
+
+	cb.mu.Lock()
+	defer cb.unlock()
+
+	// Someone else moved the circuit on while the check ran
+	if cb.generation != generation {
+		return
+	}
+
+	if err != nil {
+		cb.log().Warn("Health check failed, staying open", "error", err)
+		cb.startRecovery()
+		return
+	}
+
+	cb.log().Info("Health check passed, transitioning to closed")
+	if !cb.resetCircuit() {
+		// A vetoed close waits out another full recovery period
+		cb.startRecovery()
+	}
+}
@@ -0,0 +1,99 @@
+package cb
+
+import (
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// waitFor polls cond until it holds or a second passes
+func waitFor(t *testing.T, cond func() bool) bool {
+	t.Helper()
+
+	deadline := time.Now().Add(time.Second)
+	for !cond() {
+		if time.Now().After(deadline) {
+			return false
+		}
+		time.Sleep(time.Millisecond)
+	}
+	return true
+}
+
+func TestCircuitBreaker_HealthCheckCloses(t *testing.T) {
+	t.Parallel()
+
+	var checks atomic.Int32
+	clock := newFakeClock()
+	cb := NewCircuitBreaker(1, time.Minute, 1, time.Second, WithClock(clock), WithHealthCheck(func() error {
+		checks.Add(1)
+		return nil
+	}))
+	cb.StartProbing(time.Millisecond)
+	defer cb.StopProbing()
+
+	cb.Trip()
+	time.Sleep(20 * time.Millisecond)
+	if got := checks.Load(); got != 0 || cb.State() != Open {
+		t.Fatalf("expected no health checks within the recovery time, got %d and state %s", got, cb.State())
+	}
+
+	clock.Advance(2 * time.Minute)
+	if !waitFor(t, func() bool { return cb.State() == Closed }) {
+		t.Fatalf("expected the passing health check to close the circuit, got %s", cb.State())
+	}
+	if got := cb.Stats().Requests; got != 0 {
+		t.Fatalf("expected no user request to be consumed, got %d", got)
+	}
+}
+
+func TestCircuitBreaker_HealthCheckFails(t *testing.T) {
+	t.Parallel()
+
+	var checks atomic.Int32
+	clock := newFakeClock()
+	cb := NewCircuitBreaker(1, time.Minute, 1, time.Second, WithClock(clock), WithHealthCheck(func() error {
+		checks.Add(1)
+		return errors.New("unhealthy")
+	}))
+	cb.Trip()
+	clock.Advance(2 * time.Minute)
+
+	cb.StartProbing(time.Millisecond)
+	cb.StartProbing(time.Millisecond) // Already probing, a no-op
+	if !waitFor(t, func() bool { return checks.Load() > 0 }) {
+		t.Fatalf("expected a health check once past the recovery time")
+	}
+	cb.StopProbing()
+	cb.StopProbing() // Already stopped, a no-op
+
+	if got := checks.Load(); got != 1 {
+		t.Fatalf("expected the failed check to restart the recovery timer, got %d checks", got)
+	}
+	if cb.State() != Open {
+		t.Fatalf("expected the failing health check to keep the circuit open, got %s", cb.State())
+	}
+}
+
+func TestCircuitBreaker_StartProbingInvalidInterval(t *testing.T) {
+	t.Parallel()
+
+	cb := NewCircuitBreaker(1, time.Minute, 1, time.Second, WithHealthCheck(func() error {
+		return nil
+	}))
+
+	for _, interval := range []time.Duration{0, -time.Second} {
+		if err := cb.StartProbing(interval); !errors.Is(err, ErrInvalidConfig) {
+			t.Fatalf("expected ErrInvalidConfig for interval %v, got %v", interval, err)
+		}
+	}
+	if cb.prober != nil {
+		t.Fatalf("expected no prober to be started")
+	}
+
+	if err := cb.StartProbing(time.Millisecond); err != nil {
+		t.Fatalf("expected a positive interval to be accepted, got %v", err)
+	}
+	cb.StopProbing()
+}
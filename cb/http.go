@@ -0,0 +1,190 @@
+package cb
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// statusRange is an inclusive range of HTTP status codes
+type statusRange struct {
+	min, max int
+}
+
+// roundTripper is an http.RoundTripper that sends requests through a breaker
+type roundTripper struct {
+	cb       *circuitBreaker   // Breaker guarding the requests
+	next     http.RoundTripper // Transport that actually sends the requests
+	failures []statusRange     // Status codes counted as failures
+	reject   bool              // Whether rejections become 503 responses
+}
+
+// RoundTripperOption configures a RoundTripper created by NewRoundTripper
+type RoundTripperOption func(*roundTripper)
+
+// WithFailureStatusCodes counts responses with any of codes as failures
+func WithFailureStatusCodes(codes ...int) RoundTripperOption {
+	return func(rt *roundTripper) {
+		for _, code := range codes {
+			rt.failures = append(rt.failures, statusRange{code, code})
+		}
+	}
+}
+
+// WithFailureStatusRange counts responses with a status code between min and
+// max inclusive as failures
+func WithFailureStatusRange(min, max int) RoundTripperOption {
+	return func(rt *roundTripper) {
+		rt.failures = append(rt.failures, statusRange{min, max})
+	}
+}
+
+// WithRejectionResponse makes requests the breaker rejects come back as a
+// synthesized 503 Service Unavailable response instead of an error, for
+// clients that already handle unavailable upstreams
+func WithRejectionResponse() RoundTripperOption {
+	return func(rt *roundTripper) {
+		rt.reject = true
+	}
+}
+
+// statusError signals a response whose status code counts as a failure
+type statusError struct {
+	code int
+}
+
+func (e *statusError) Error() string {
+	return fmt.Sprintf("failure status code %d", e.code)
+}
+
+// NewRoundTripper wraps next so that every request goes through cb. Transport
+// errors and, by default, 5xx responses count as failures, while 4xx
+// responses are the caller's fault and don't. Configuring any failure status
+// codes or ranges replaces the 5xx default. Responses are returned as usual
+// whatever their status, and when the breaker rejects a request its error is
+// returned without touching the network. A fallback set on cb has to serve an
+// *http.Response; anything else is returned as an error. A nil next uses
+// http.DefaultTransport.
+func NewRoundTripper(cb *circuitBreaker, next http.RoundTripper, opts ...RoundTripperOption) http.RoundTripper {
+	if next == nil {
+		next = http.DefaultTransport
+	}
+
+	rt := &roundTripper{cb: cb, next: next}
+	for _, opt := range opts {
+		opt(rt)
+	}
+
+	if len(rt.failures) == 0 {
+		rt.failures = []statusRange{{500, 599}}
+	}
+	return rt
+}
+
+// RoundTrip sends req through the breaker, cancelling the wait along with the
+// request's context. The request is sent with the context of the call, so the
+// breaker's timeout cancels it too.
+func (rt *roundTripper) RoundTrip(req *http.Request) (*http.Response, error) {
+	var (
+		mu        sync.Mutex
+		resp      *http.Response
+		abandoned bool // Whether RoundTrip returned without waiting for the response
+	)
+
+	result, err := rt.cb.CallContext(req.Context(), func(ctx context.Context) (any, error) {
+		r, err := rt.next.RoundTrip(req.WithContext(ctx))
+		if err != nil {
+			return nil, err
+		}
+
+		mu.Lock()
+		defer mu.Unlock()
+		if abandoned {
+			// Nobody is left to read the body of a response that came too late
+			r.Body.Close()
+			return nil, ErrTimeout
+		}
+		resp = r
+		if rt.isFailure(r.StatusCode) {
+			return nil, &statusError{code: r.StatusCode}
+		}
+		return r, nil
+	})
+
+	mu.Lock()
+	abandoned = true
+	r := resp
+	mu.Unlock()
+
+	var statusErr *statusError
+	if errors.As(err, &statusErr) {
+		return r, nil
+	}
+	if err == nil {
+		// The result is either the response or whatever a fallback served
+		// instead of it
+		served, _ := result.(*http.Response)
+		if r != nil && r != served {
+			r.Body.Close()
+		}
+		if served == nil {
+			return nil, fmt.Errorf("circuit breaker returned %T instead of an *http.Response", result)
+		}
+		return served, nil
+	}
+	if r != nil {
+		// The breaker discarded a response that arrived as it gave up
+		r.Body.Close()
+	}
+	if rt.reject && isRejection(err) {
+		return unavailable(req, err), nil
+	}
+	return nil, err
+}
+
+// isRejection reports whether err means the breaker turned the request away
+// without sending it
+func isRejection(err error) bool {
+	for _, target := range []error{ErrCircuitOpen, ErrHalfOpenBudgetExceeded, ErrConcurrencyLimit, ErrRateLimited, ErrLoadShed, ErrRetryBudgetExceeded, ErrShutdown} {
+		if errors.Is(err, target) {
+			return true
+		}
+	}
+	return false
+}
+
+// unavailable synthesizes a 503 response to req, with a Retry-After header in
+// whole seconds when err says how long the circuit stays open
+func unavailable(req *http.Request, err error) *http.Response {
+	header := http.Header{}
+	var openErr *OpenError
+	if errors.As(err, &openErr) && openErr.RetryAfter() > 0 {
+		seconds := (openErr.RetryAfter() + time.Second - 1) / time.Second
+		header.Set("Retry-After", strconv.Itoa(int(seconds)))
+	}
+
+	return &http.Response{
+		Status:     fmt.Sprintf("%d %s", http.StatusServiceUnavailable, http.StatusText(http.StatusServiceUnavailable)),
+		StatusCode: http.StatusServiceUnavailable,
+		Proto:      "HTTP/1.1",
+		ProtoMajor: 1,
+		ProtoMinor: 1,
+		Header:     header,
+		Body:       http.NoBody,
+		Request:    req,
+	}
+}
+
+// isFailure reports whether a response with status code counts as a failure
+func (rt *roundTripper) isFailure(code int) bool {
+	for _, r := range rt.failures {
+		if code >= r.min && code <= r.max {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,320 @@
+package cb
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// newStatusServer responds to /<code> with that status code
+func newStatusServer(t *testing.T) *httptest.Server {
+	t.Helper()
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		code, _ := strconv.Atoi(strings.TrimPrefix(r.URL.Path, "/"))
+		w.WriteHeader(code)
+	}))
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+// failuresAfter sends a request for each code through a fresh breaker and
+// returns the breaker's failure count
+func failuresAfter(t *testing.T, srv *httptest.Server, codes []int, opts ...RoundTripperOption) int {
+	t.Helper()
+
+	cb := NewCircuitBreaker(100, time.Second, 1, 2*time.Second)
+	client := &http.Client{Transport: NewRoundTripper(cb, http.DefaultTransport, opts...)}
+
+	for _, code := range codes {
+		resp, err := client.Get(srv.URL + "/" + strconv.Itoa(code))
+		if err != nil {
+			t.Fatalf("expected a response for %d, got %v", code, err)
+		}
+		resp.Body.Close()
+
+		if resp.StatusCode != code {
+			t.Fatalf("expected status %d, got %d", code, resp.StatusCode)
+		}
+	}
+	return cb.Counts().TotalFailures
+}
+
+func TestRoundTripper_DefaultStatusClassification(t *testing.T) {
+	t.Parallel()
+
+	srv := newStatusServer(t)
+
+	if got := failuresAfter(t, srv, []int{200, 201, 204, 301}); got != 0 {
+		t.Fatalf("expected 2xx and 3xx not to count, got %d failures", got)
+	}
+
+	if got := failuresAfter(t, srv, []int{400, 404, 429}); got != 0 {
+		t.Fatalf("expected 4xx not to count by default, got %d failures", got)
+	}
+
+	if got := failuresAfter(t, srv, []int{500, 502, 503}); got != 3 {
+		t.Fatalf("expected 5xx to count by default, got %d failures", got)
+	}
+}
+
+func TestRoundTripper_ConfiguredStatusClassification(t *testing.T) {
+	t.Parallel()
+
+	srv := newStatusServer(t)
+	opts := []RoundTripperOption{
+		WithFailureStatusCodes(429),
+		WithFailureStatusRange(502, 504),
+	}
+
+	if got := failuresAfter(t, srv, []int{429}, opts...); got != 1 {
+		t.Fatalf("expected 429 to count, got %d failures", got)
+	}
+
+	if got := failuresAfter(t, srv, []int{502, 503, 504}, opts...); got != 3 {
+		t.Fatalf("expected 502-504 to count, got %d failures", got)
+	}
+
+	// Configuring codes replaces the 5xx default
+	if got := failuresAfter(t, srv, []int{400, 500, 501}, opts...); got != 0 {
+		t.Fatalf("expected unconfigured codes not to count, got %d failures", got)
+	}
+}
+
+func TestRoundTripper_OpenCircuit(t *testing.T) {
+	t.Parallel()
+
+	var hits atomic.Int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		hits.Add(1)
+		w.WriteHeader(http.StatusInternalServerError)
+	}))
+	defer srv.Close()
+
+	cb := NewCircuitBreaker(1, time.Minute, 1, 2*time.Second)
+	client := &http.Client{Transport: NewRoundTripper(cb, http.DefaultTransport)}
+
+	resp, err := client.Get(srv.URL)
+	if err != nil {
+		t.Fatalf("expected the failing response to be returned, got %v", err)
+	}
+	resp.Body.Close()
+
+	_, err = client.Get(srv.URL)
+	if !errors.Is(err, ErrCircuitOpen) {
+		t.Fatalf("expected ErrCircuitOpen, got %v", err)
+	}
+
+	if got := hits.Load(); got != 1 {
+		t.Fatalf("expected the open circuit to skip the network, got %d hits", got)
+	}
+}
+
+func TestRoundTripper_RejectionResponseNotPickedAsProbe(t *testing.T) {
+	t.Parallel()
+
+	var hits atomic.Int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		hits.Add(1)
+	}))
+	defer srv.Close()
+
+	cb := NewCircuitBreaker(1, time.Minute, 1, 2*time.Second, WithShouldProbe(func() bool { return false }))
+	cb.setState(HalfOpen)
+	client := &http.Client{Transport: NewRoundTripper(cb, nil, WithRejectionResponse())}
+
+	resp, err := client.Get(srv.URL)
+	if err != nil {
+		t.Fatalf("expected a response, got %v", err)
+	}
+	resp.Body.Close()
+
+	if resp.StatusCode != http.StatusServiceUnavailable {
+		t.Fatalf("expected status %d, got %d", http.StatusServiceUnavailable, resp.StatusCode)
+	}
+	if got := hits.Load(); got != 0 {
+		t.Fatalf("expected the declined probe to skip the network, got %d hits", got)
+	}
+}
+
+func TestRoundTripper_RejectionResponse(t *testing.T) {
+	t.Parallel()
+
+	var hits atomic.Int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		hits.Add(1)
+		w.WriteHeader(http.StatusInternalServerError)
+	}))
+	defer srv.Close()
+
+	cb := NewCircuitBreaker(1, time.Minute, 1, 2*time.Second)
+	client := &http.Client{Transport: NewRoundTripper(cb, nil, WithRejectionResponse())}
+
+	for i := 0; i < 2; i++ {
+		resp, err := client.Get(srv.URL)
+		if err != nil {
+			t.Fatalf("expected a response, got %v", err)
+		}
+		resp.Body.Close()
+
+		want := http.StatusInternalServerError
+		if i == 1 {
+			want = http.StatusServiceUnavailable
+		}
+		if resp.StatusCode != want {
+			t.Fatalf("expected status %d, got %d", want, resp.StatusCode)
+		}
+		if i == 1 && resp.Header.Get("Retry-After") != "60" {
+			t.Fatalf("expected Retry-After 60, got %q", resp.Header.Get("Retry-After"))
+		}
+	}
+
+	if got := hits.Load(); got != 1 {
+		t.Fatalf("expected the open circuit to skip the network, got %d hits", got)
+	}
+}
+
+func TestRoundTripper_RequestContext(t *testing.T) {
+	t.Parallel()
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer srv.Close()
+
+	cb := NewCircuitBreaker(1, time.Minute, 1, 2*time.Second)
+	client := &http.Client{Transport: NewRoundTripper(cb, http.DefaultTransport)}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	req, _ := http.NewRequestWithContext(ctx, http.MethodGet, srv.URL, nil)
+
+	if _, err := client.Do(req); !errors.Is(err, context.Canceled) {
+		t.Fatalf("expected the cancelled request to fail with context.Canceled, got %v", err)
+	}
+	if got := cb.Counts().Requests; got != 0 {
+		t.Fatalf("expected the cancelled request not to reach the breaker, got %d requests", got)
+	}
+}
+
+func TestRoundTripper_TimeoutCancelsRequest(t *testing.T) {
+	t.Parallel()
+
+	canceled := make(chan struct{})
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case <-r.Context().Done():
+			close(canceled)
+		case <-time.After(5 * time.Second):
+		}
+	}))
+	defer srv.Close()
+
+	cb := NewCircuitBreaker(1, time.Minute, 1, 50*time.Millisecond)
+	client := &http.Client{Transport: NewRoundTripper(cb, http.DefaultTransport)}
+
+	if _, err := client.Get(srv.URL); !errors.Is(err, ErrTimeout) {
+		t.Fatalf("expected ErrTimeout, got %v", err)
+	}
+
+	select {
+	case <-canceled:
+	case <-time.After(time.Second):
+		t.Fatalf("expected the breaker's timeout to cancel the request")
+	}
+}
+
+// roundTripFunc is an http.RoundTripper made from a function
+type roundTripFunc func(*http.Request) (*http.Response, error)
+
+func (f roundTripFunc) RoundTrip(req *http.Request) (*http.Response, error) {
+	return f(req)
+}
+
+// closeSignal is a response body that signals when it's closed
+type closeSignal struct {
+	io.Reader
+	closed chan struct{}
+}
+
+func (b *closeSignal) Close() error {
+	close(b.closed)
+	return nil
+}
+
+func TestRoundTripper_LateResponseClosed(t *testing.T) {
+	t.Parallel()
+
+	body := &closeSignal{Reader: strings.NewReader("late"), closed: make(chan struct{})}
+	next := roundTripFunc(func(*http.Request) (*http.Response, error) {
+		// Ignores the request's context, like a transport stuck on a slow peer
+		time.Sleep(100 * time.Millisecond)
+		return &http.Response{StatusCode: http.StatusOK, Body: body}, nil
+	})
+
+	cb := NewCircuitBreaker(1, time.Minute, 1, 20*time.Millisecond)
+	client := &http.Client{Transport: NewRoundTripper(cb, next)}
+
+	if _, err := client.Get("http://example.invalid"); !errors.Is(err, ErrTimeout) {
+		t.Fatalf("expected ErrTimeout, got %v", err)
+	}
+
+	select {
+	case <-body.closed:
+	case <-time.After(time.Second):
+		t.Fatalf("expected the body of the discarded response to be closed")
+	}
+}
+
+func TestRoundTripper_Fallback(t *testing.T) {
+	t.Parallel()
+
+	var hits atomic.Int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		hits.Add(1)
+	}))
+	defer srv.Close()
+
+	cb := NewCircuitBreaker(1, time.Minute, 1, 2*time.Second, WithFallback(func(error) (any, error) {
+		return &http.Response{StatusCode: http.StatusOK, Body: io.NopCloser(strings.NewReader("cached"))}, nil
+	}))
+	cb.Trip()
+	client := &http.Client{Transport: NewRoundTripper(cb, nil)}
+
+	resp, err := client.Get(srv.URL)
+	if err != nil {
+		t.Fatalf("expected the fallback's response, got %v", err)
+	}
+	body, _ := io.ReadAll(resp.Body)
+	resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK || string(body) != "cached" {
+		t.Fatalf("expected the cached response, got %d %q", resp.StatusCode, body)
+	}
+	if got := hits.Load(); got != 0 {
+		t.Fatalf("expected the open circuit to skip the network, got %d hits", got)
+	}
+}
+
+func TestRoundTripper_FallbackWithoutResponse(t *testing.T) {
+	t.Parallel()
+
+	cb := NewCircuitBreaker(1, time.Minute, 1, 2*time.Second, WithFallback(func(error) (any, error) {
+		return "cached", nil
+	}))
+	cb.Trip()
+	rt := NewRoundTripper(cb, nil)
+
+	req, _ := http.NewRequest(http.MethodGet, "http://example.invalid", nil)
+	resp, err := rt.RoundTrip(req)
+	if resp != nil || err == nil || !strings.Contains(err.Error(), "instead of an *http.Response") {
+		t.Fatalf("expected an error for a fallback that served no response, got %v, %v", resp, err)
+	}
+}
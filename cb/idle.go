@@ -0,0 +1,33 @@
+package cb
+
+import "time"
+
+// WithIdleReset makes the breaker forget its state once no call has arrived
+// for d. The next call after such a quiet period finds the circuit closed with
+// fresh counts, giving the dependency a new chance instead of being judged on
+// stale history.
+func WithIdleReset(d time.Duration) Option {
+	return func(cb *circuitBreaker) {
+		cb.idleReset = d
+	}
+}
+
+// resetIfIdle resets the breaker if it's been idle for longer than the idle
+// reset period, then marks it active
+func (cb *circuitBreaker) resetIfIdle(c *call) {
+	now := cb.clock.Now()
+	idle := !cb.lastActivity.IsZero() && now.Sub(cb.lastActivity) >= cb.idleReset
+	cb.lastActivity = now
+
+	if cb.idleReset <= 0 || !idle {
+		return
+	}
+
+	if cb.state != Closed {
+		c.log.Info("Breaker idle past the reset period, resetting", "state", cb.state)
+		cb.resetCircuit()
+		return
+	}
+
+	cb.clearCounts()
+}
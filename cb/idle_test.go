@@ -0,0 +1,62 @@
+package cb
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestCircuitBreaker_IdleReset(t *testing.T) {
+	t.Parallel()
+
+	clock := newFakeClock()
+	cb := NewCircuitBreaker(1, time.Hour, 1, 2*time.Second, WithClock(clock), WithIdleReset(10*time.Minute))
+
+	successFn := func() (any, error) {
+		return 42, nil
+	}
+
+	_, _ = cb.Call(func() (any, error) {
+		return nil, errFailure
+	})
+
+	// Calls keep the breaker active, so it stays open
+	clock.Advance(9 * time.Minute)
+	if _, err := cb.Call(successFn); !errors.Is(err, ErrCircuitOpen) {
+		t.Fatalf("expected the circuit to stay open while active, got %v", err)
+	}
+
+	clock.Advance(9 * time.Minute)
+	if _, err := cb.Call(successFn); !errors.Is(err, ErrCircuitOpen) {
+		t.Fatalf("expected the circuit to stay open while active, got %v", err)
+	}
+
+	// A long quiet period resets it well before the recovery time is up
+	clock.Advance(10 * time.Minute)
+	if _, err := cb.Call(successFn); err != nil {
+		t.Fatalf("expected the call after the idle period to run, got %v", err)
+	}
+
+	if cb.state != Closed {
+		t.Fatalf("expected state closed after the idle reset, got %s", cb.state)
+	}
+}
+
+func TestCircuitBreaker_IdleResetClearsClosedCounts(t *testing.T) {
+	t.Parallel()
+
+	clock := newFakeClock()
+	cb := NewCircuitBreaker(2, time.Hour, 1, 2*time.Second, WithClock(clock), WithIdleReset(10*time.Minute))
+
+	failFn := func() (any, error) {
+		return nil, errFailure
+	}
+
+	_, _ = cb.Call(failFn)
+	clock.Advance(time.Hour)
+	_, _ = cb.Call(failFn)
+
+	if cb.state != Closed || cb.counts.ConsecutiveFailures != 1 {
+		t.Fatalf("expected the stale failure to be forgotten, got %s %+v", cb.state, cb.counts)
+	}
+}
@@ -0,0 +1,20 @@
+package cb
+
+import "time"
+
+// WithRecoveryJitter adds a random delay of up to jitter to the recovery time,
+// drawn anew every time the circuit opens, so that a fleet of breakers that
+// tripped together doesn't probe the recovering dependency all at once.
+func WithRecoveryJitter(jitter time.Duration) Option {
+	return func(cb *circuitBreaker) {
+		cb.recoveryJitter = jitter
+	}
+}
+
+// drawJitter picks the extra recovery delay of the open episode starting now
+func (cb *circuitBreaker) drawJitter() {
+	cb.jitter = 0
+	if cb.recoveryJitter > 0 {
+		cb.jitter = time.Duration(cb.rand.Int63n(int64(cb.recoveryJitter) + 1))
+	}
+}
@@ -0,0 +1,52 @@
+package cb
+
+import (
+	"errors"
+	"math/rand"
+	"testing"
+	"time"
+)
+
+func TestCircuitBreaker_RecoveryJitter(t *testing.T) {
+	t.Parallel()
+
+	const recovery, jitter = 10 * time.Second, 5 * time.Second
+	cb := NewCircuitBreaker(1, recovery, 1, time.Second, WithRecoveryJitter(jitter), WithRandSource(rand.NewSource(1)))
+
+	windows := map[time.Duration]bool{}
+	for i := 0; i < 50; i++ {
+		cb.Trip()
+		window := cb.recoveryWindow()
+		if window < recovery || window > recovery+jitter {
+			t.Fatalf("expected a recovery window within [%s, %s], got %s", recovery, recovery+jitter, window)
+		}
+		windows[window] = true
+		cb.Reset()
+	}
+
+	if len(windows) < 2 {
+		t.Fatalf("expected the jitter to be drawn anew on each trip, got %v", windows)
+	}
+}
+
+func TestCircuitBreaker_RecoveryJitterDelaysProbing(t *testing.T) {
+	t.Parallel()
+
+	clock := newFakeClock()
+	cb := NewCircuitBreaker(1, time.Second, 1, time.Second, WithClock(clock), WithRecoveryJitter(time.Minute))
+
+	_, _ = cb.Call(func() (any, error) {
+		return nil, errFailure
+	})
+	window := cb.recoveryWindow()
+
+	clock.Advance(window)
+	if _, err := cb.Call(func() (any, error) { return 42, nil }); !errors.Is(err, ErrCircuitOpen) {
+		t.Fatalf("expected the circuit to stay open for the jittered %s, got %v", window, err)
+	}
+
+	clock.Advance(time.Millisecond)
+	if _, err := cb.Call(func() (any, error) { return 42, nil }); err != nil {
+		t.Fatalf("expected a probe once the jittered window passed, got %v", err)
+	}
+}
@@ -0,0 +1,70 @@
+package cb
+
+import (
+	"math"
+	"slices"
+	"time"
+)
+
+// defaultLatencyReservoirSize is the number of latency samples kept by default
+const defaultLatencyReservoirSize = 256
+
+// latencyReservoir keeps a fixed-size uniform random sample of the observed
+// latencies using reservoir sampling (Algorithm R), so percentiles stay
+// representative under any throughput without unbounded growth.
+//
+// The sample covers the breaker's whole lifetime, so it reacts slowly to a
+// recent shift in latency, and with a small reservoir the extreme tail rests
+// on only a handful of samples. A reservoir of 256 puts a p99 estimate on
+// roughly 3 samples.
+type latencyReservoir struct {
+	samples []time.Duration // Sampled latencies, at most size of them
+	size    int             // Capacity of the reservoir
+	seen    int64           // Number of latencies observed so far
+}
+
+// newLatencyReservoir creates an empty reservoir holding up to size samples
+func newLatencyReservoir(size int) *latencyReservoir {
+	return &latencyReservoir{
+		samples: make([]time.Duration, 0, size),
+		size:    size,
+	}
+}
+
+// observe offers d to the reservoir, replacing a random sample once full
+func (r *latencyReservoir) observe(d time.Duration, rnd *lockedRand) {
+	r.seen++
+	if len(r.samples) < r.size {
+		r.samples = append(r.samples, d)
+		return
+	}
+
+	if i := rnd.Int63n(r.seen); i < int64(r.size) {
+		r.samples[i] = d
+	}
+}
+
+// percentile returns the nearest-rank q-th percentile, q in [0, 1], of the
+// sampled latencies, or zero if nothing was observed
+func (r *latencyReservoir) percentile(q float64) time.Duration {
+	if len(r.samples) == 0 {
+		return 0
+	}
+
+	sorted := slices.Clone(r.samples)
+	slices.Sort(sorted)
+
+	rank := int(math.Ceil(q * float64(len(sorted))))
+	rank = min(max(rank, 1), len(sorted))
+	return sorted[rank-1]
+}
+
+// LatencyPercentile returns the estimated q-th percentile, q in [0, 1], of the
+// latency of calls that ran to completion, or zero if none have. Timed out
+// calls aren't sampled since their true latency is unknown.
+func (cb *circuitBreaker) LatencyPercentile(q float64) time.Duration {
+	cb.mu.Lock()
+	defer cb.mu.Unlock()
+
+	return cb.latencies.percentile(q)
+}
@@ -0,0 +1,85 @@
+package cb
+
+import (
+	"math/rand"
+	"testing"
+	"time"
+)
+
+func TestLatencyReservoir_SkewedPercentiles(t *testing.T) {
+	t.Parallel()
+
+	rnd := newLockedRand(rand.NewSource(42))
+	r := newLatencyReservoir(defaultLatencyReservoirSize)
+
+	// 95% of calls take 1-10ms, the slowest 5% take 400-600ms
+	gen := rand.New(rand.NewSource(1))
+	for i := 0; i < 100_000; i++ {
+		var d time.Duration
+		if gen.Float64() < 0.95 {
+			d = time.Millisecond + time.Duration(gen.Int63n(int64(9*time.Millisecond)))
+		} else {
+			d = 400*time.Millisecond + time.Duration(gen.Int63n(int64(200*time.Millisecond)))
+		}
+		r.observe(d, rnd)
+	}
+
+	if len(r.samples) != defaultLatencyReservoirSize {
+		t.Fatalf("expected %d samples, got %d", defaultLatencyReservoirSize, len(r.samples))
+	}
+
+	if p50 := r.percentile(0.5); p50 < time.Millisecond || p50 > 10*time.Millisecond {
+		t.Fatalf("expected p50 within 1-10ms, got %v", p50)
+	}
+
+	if p99 := r.percentile(0.99); p99 < 400*time.Millisecond || p99 > 600*time.Millisecond {
+		t.Fatalf("expected p99 within 400-600ms, got %v", p99)
+	}
+}
+
+func TestLatencyReservoir_Empty(t *testing.T) {
+	t.Parallel()
+
+	r := newLatencyReservoir(4)
+
+	if p := r.percentile(0.99); p != 0 {
+		t.Fatalf("expected zero percentile for an empty reservoir, got %v", p)
+	}
+}
+
+func TestCircuitBreaker_LatencyReservoir(t *testing.T) {
+	t.Parallel()
+
+	clock := newFakeClock()
+	cb := NewCircuitBreaker(3, time.Second, 1, 2*time.Second, WithClock(clock), WithLatencyReservoir(8))
+
+	for i := 1; i <= 20; i++ {
+		_, _ = cb.Call(func() (any, error) {
+			clock.Advance(time.Duration(i) * time.Millisecond)
+			return nil, nil
+		})
+	}
+
+	if len(cb.latencies.samples) != 8 {
+		t.Fatalf("expected the reservoir to hold 8 samples, got %d", len(cb.latencies.samples))
+	}
+
+	if p := cb.LatencyPercentile(1); p < time.Millisecond || p > 20*time.Millisecond {
+		t.Fatalf("expected max latency within 1-20ms, got %v", p)
+	}
+}
+
+func TestCircuitBreaker_LatencyReservoirNonPositive(t *testing.T) {
+	t.Parallel()
+
+	for _, size := range []int{0, -1} {
+		cb := NewCircuitBreaker(3, time.Second, 1, 2*time.Second, WithLatencyReservoir(size))
+
+		_, _ = cb.Call(func() (any, error) {
+			return nil, nil
+		})
+		if len(cb.latencies.samples) != 1 {
+			t.Fatalf("expected size %d to keep a single sample, got %d", size, len(cb.latencies.samples))
+		}
+	}
+}
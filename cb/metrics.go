@@ -0,0 +1,68 @@
+package cb
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// totals holds cumulative counters that, unlike Counts, survive state
+// transitions
+type totals struct {
+	requests   int // Number of calls made through the breaker
+	successes  int // Number of calls that succeeded
+	failures   int // Number of calls that failed
+	rejections int // Number of calls rejected without running
+	timeouts   int // Number of calls that timed out, also counted as failures
+	throttled  int // Number of calls rejected by the rate limit, also counted as rejections
+	canceled   int // Number of calls the caller gave up on, counted neither way
+	slow       int // Number of successful calls slower than the slow call threshold
+}
+
+// metricsStates lists the states reported by the state gauge, in order
+var metricsStates = []State{Closed, Open, HalfOpen, Disabled}
+
+// labelEscaper escapes a label value for the Prometheus text format
+var labelEscaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+
+// WriteMetrics writes the breaker's cumulative counters and current state to w
+// in the Prometheus text exposition format
+func (cb *circuitBreaker) WriteMetrics(w io.Writer) error {
+	cb.mu.Lock()
+	cb.foldRejections()
+	name, state, t := cb.name, cb.reportedState(), cb.totals
+	cb.mu.Unlock()
+
+	label := fmt.Sprintf(`name="%s"`, labelEscaper.Replace(name))
+	bw := bufio.NewWriter(w)
+
+	counters := []struct {
+		name  string
+		help  string
+		value int
+	}{
+		{"circuit_breaker_requests_total", "Total number of calls made through the circuit breaker.", t.requests},
+		{"circuit_breaker_successes_total", "Total number of calls that succeeded.", t.successes},
+		{"circuit_breaker_failures_total", "Total number of calls that failed.", t.failures},
+		{"circuit_breaker_rejections_total", "Total number of calls rejected without running.", t.rejections},
+		{"circuit_breaker_timeouts_total", "Total number of calls that timed out.", t.timeouts},
+	}
+	for _, c := range counters {
+		fmt.Fprintf(bw, "# HELP %s %s\n", c.name, c.help)
+		fmt.Fprintf(bw, "# TYPE %s counter\n", c.name)
+		fmt.Fprintf(bw, "%s{%s} %d\n", c.name, label, c.value)
+	}
+
+	fmt.Fprintln(bw, "# HELP circuit_breaker_state Current state of the circuit breaker, 1 for the active state.")
+	fmt.Fprintln(bw, "# TYPE circuit_breaker_state gauge")
+	for _, s := range metricsStates {
+		value := 0
+		if s == state {
+			value = 1
+		}
+		fmt.Fprintf(bw, "circuit_breaker_state{%s,state=\"%s\"} %d\n", label, s, value)
+	}
+
+	return bw.Flush()
+}
@@ -0,0 +1,72 @@
+package cb
+
+import (
+	"bytes"
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+)
+
+var (
+	metricsCommentRe = regexp.MustCompile(`^# (HELP [a-zA-Z_:][a-zA-Z0-9_:]* .+|TYPE [a-zA-Z_:][a-zA-Z0-9_:]* (counter|gauge))$`)
+	metricsSampleRe  = regexp.MustCompile(`^([a-zA-Z_:][a-zA-Z0-9_:]*)\{([a-zA-Z_][a-zA-Z0-9_]*="(?:[^"\\\n]|\\[\\"n])*"(?:,[a-zA-Z_][a-zA-Z0-9_]*="(?:[^"\\\n]|\\[\\"n])*")*)\} -?[0-9]+$`)
+)
+
+func TestCircuitBreaker_WriteMetrics(t *testing.T) {
+	t.Parallel()
+
+	cb := NewCircuitBreaker(1, 5*time.Second, 1, 2*time.Second, WithName("pay\"ments\\\nv2"))
+
+	successFn := func() (any, error) {
+		return 42, nil
+	}
+	failFn := func() (any, error) {
+		return nil, errFailure
+	}
+
+	_, _ = cb.Call(successFn)
+	_, _ = cb.Call(failFn)
+	_, _ = cb.Call(successFn) // Rejected, the circuit is open
+
+	var buf bytes.Buffer
+	if err := cb.WriteMetrics(&buf); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+
+	typed := map[string]bool{}
+	for _, line := range strings.Split(strings.TrimSuffix(buf.String(), "\n"), "\n") {
+		if strings.HasPrefix(line, "#") {
+			if !metricsCommentRe.MatchString(line) {
+				t.Fatalf("invalid comment line %q", line)
+			}
+			if fields := strings.Fields(line); fields[1] == "TYPE" {
+				typed[fields[2]] = true
+			}
+			continue
+		}
+
+		m := metricsSampleRe.FindStringSubmatch(line)
+		if m == nil {
+			t.Fatalf("invalid sample line %q", line)
+		}
+		if !typed[m[1]] {
+			t.Fatalf("sample %q appears before its TYPE line", m[1])
+		}
+	}
+
+	out := buf.String()
+	for _, want := range []string{
+		`circuit_breaker_requests_total{name="pay\"ments\\\nv2"} 3`,
+		`circuit_breaker_successes_total{name="pay\"ments\\\nv2"} 1`,
+		`circuit_breaker_failures_total{name="pay\"ments\\\nv2"} 1`,
+		`circuit_breaker_rejections_total{name="pay\"ments\\\nv2"} 1`,
+		`circuit_breaker_timeouts_total{name="pay\"ments\\\nv2"} 0`,
+		`circuit_breaker_state{name="pay\"ments\\\nv2",state="open"} 1`,
+		`circuit_breaker_state{name="pay\"ments\\\nv2",state="closed"} 0`,
+	} {
+		if !strings.Contains(out, want+"\n") {
+			t.Fatalf("expected output to contain %q, got:\n%s", want, out)
+		}
+	}
+}
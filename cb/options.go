@@ -0,0 +1,202 @@
+package cb
+
+import (
+	"log/slog"
+	"math/rand"
+	"time"
+)
+
+// Option configures optional behavior of the circuit breaker
+type Option func(*circuitBreaker)
+
+// WithFailureThreshold sets the number of failures that trips the circuit: the
+// circuit opens on the failure that brings the count to n, so with 1 the very
+// first failure trips it and with 2 the second one does. Values below 1 count
+// as 1, and Builder rejects them.
+func WithFailureThreshold(n int) Option {
+	return func(cb *circuitBreaker) {
+		cb.failureThreshold = n
+	}
+}
+
+// WithRecoveryTime sets how long the open circuit waits before probing
+func WithRecoveryTime(d time.Duration) Option {
+	return func(cb *circuitBreaker) {
+		cb.recoveryTime = d
+	}
+}
+
+// WithHalfOpenMaxRequests sets the number of probes the half-open circuit
+// admits. Unless WithSuccessesToClose says otherwise, that many must succeed
+// for the circuit to close.
+func WithHalfOpenMaxRequests(n int) Option {
+	return func(cb *circuitBreaker) {
+		cb.halfOpenMaxRequests = n
+	}
+}
+
+// WithSuccessesToClose sets the number of consecutive successful probes that
+// closes the half-open circuit, so that it can admit more probes than it needs
+// to recover. It's capped at the half-open max requests, since the circuit
+// couldn't admit enough probes otherwise. Zero means the half-open max
+// requests.
+func WithSuccessesToClose(n int) Option {
+	return func(cb *circuitBreaker) {
+		cb.successesToClose = n
+	}
+}
+
+// WithTimeout sets how long a request may run before it times out. A zero or
+// negative timeout disables it.
+func WithTimeout(d time.Duration) Option {
+	return func(cb *circuitBreaker) {
+		cb.timeout = d
+	}
+}
+
+// WithName sets the name that identifies the circuit breaker in metrics
+func WithName(name string) Option {
+	return func(cb *circuitBreaker) {
+		cb.name = name
+	}
+}
+
+// WithClock sets the clock the circuit breaker uses to tell time
+func WithClock(clock Clock) Option {
+	return func(cb *circuitBreaker) {
+		cb.clock = clock
+	}
+}
+
+// WithRequestRateLimit caps the number of requests allowed through the closed
+// circuit to n per interval. Requests beyond the limit are rejected with
+// ErrRateLimited and don't count as failures. An n or interval of zero or less
+// sets no limit, and Builder rejects it.
+func WithRequestRateLimit(n int, interval time.Duration) Option {
+	return func(cb *circuitBreaker) {
+		cb.limit = &rateLimit{n: n, interval: interval}
+		cb.limiter = nil
+		if n > 0 && interval > 0 {
+			cb.limiter = newTokenBucket(n, interval)
+		}
+	}
+}
+
+// WithRandSource sets the source of randomness shared by every randomized
+// behavior of the circuit breaker. Pass a fixed seed for reproducible tests.
+func WithRandSource(src rand.Source) Option {
+	return func(cb *circuitBreaker) {
+		cb.rand = newLockedRand(src)
+	}
+}
+
+// WithFirstProbeResult registers a hook that observes the outcome of the first
+// probe after each transition to half-open, the most telling signal of whether
+// the dependency has recovered. It fires exactly once per recovery cycle, after
+// the breaker's lock is released.
+func WithFirstProbeResult(hook func(result any, err error)) Option {
+	return func(cb *circuitBreaker) {
+		cb.onFirstProbe = hook
+	}
+}
+
+// WithOnReject registers hook to be called each time the open circuit blocks a
+// request, for tracking the rejection rate. Stats.Rejections keeps the running
+// total. The hook runs synchronously on the calling goroutine after the
+// breaker's lock is released.
+func WithOnReject(hook func()) Option {
+	return func(cb *circuitBreaker) {
+		cb.onReject = hook
+	}
+}
+
+// WithLatencyReservoir sets how many latency samples the breaker keeps for
+// percentile estimates, trading accuracy for memory. Defaults to 256. Sizes
+// below 1 keep a single sample, and Builder rejects them.
+func WithLatencyReservoir(size int) Option {
+	return func(cb *circuitBreaker) {
+		cb.latencySize = size
+		cb.latencies = newLatencyReservoir(max(size, 1))
+	}
+}
+
+// WithTimeoutRacePreference decides the tie when a call's result and its
+// timeout arrive together. With preferResult set, a result that's ready by the
+// deadline is returned instead of a timeout error.
+func WithTimeoutRacePreference(preferResult bool) Option {
+	return func(cb *circuitBreaker) {
+		cb.preferResult = preferResult
+	}
+}
+
+// WithReadyToTrip replaces the trip decision with readyToTrip, which is passed
+// the closed circuit's counts after every failure and opens the circuit when
+// it returns true. It takes precedence over the failure threshold, window and
+// rate, so any policy can be built from the counts, e.g. 5 consecutive
+// failures or 60% of at least 10 requests failing. It runs while the
+// breaker's lock is held, so it must not call back into the breaker.
+func WithReadyToTrip(readyToTrip func(counts Counts) bool) Option {
+	return func(cb *circuitBreaker) {
+		cb.customTrip = readyToTrip
+	}
+}
+
+// WithTransitionGuard registers guard to be consulted before every state
+// transition. Returning false cancels the transition and the breaker stays in
+// its current state: a vetoed recovery from open waits another full recovery
+// period, and a vetoed close from half-open starts a fresh round of probes.
+// The guard runs while the breaker's lock is held, so it must not call back
+// into the breaker.
+func WithTransitionGuard(guard func(from, to State) bool) Option {
+	return func(cb *circuitBreaker) {
+		cb.transitionGuard = guard
+	}
+}
+
+// WithOnStateChange registers hook to be called with the old and new state
+// whenever the breaker transitions, e.g. to emit a metric or page someone. It
+// runs synchronously on the goroutine whose call caused the transition, after
+// the breaker's lock is released, so it may call back into the breaker but
+// delays that caller until it returns.
+func WithOnStateChange(hook func(from, to State)) Option {
+	return func(cb *circuitBreaker) {
+		cb.onStateChange = hook
+	}
+}
+
+// WithSeverityWeighting makes each failure count toward the trip threshold
+// with the weight returned by weight, so that severe errors trip the breaker
+// sooner than mild ones. A weight of 1 counts like a failure without
+// weighting.
+func WithSeverityWeighting(weight func(err error) float64) Option {
+	return func(cb *circuitBreaker) {
+		cb.failureWeight = weight
+	}
+}
+
+// WithWeightedFailureThreshold sets the failure score that trips a circuit
+// whose failures are weighted, for thresholds that aren't whole numbers, e.g.
+// 2.5 with refused connections weighing 2 and 503s 0.5. It's ignored without
+// weighting. Zero, the default, uses the failure threshold.
+func WithWeightedFailureThreshold(score float64) Option {
+	return func(cb *circuitBreaker) {
+		cb.weightedThreshold = score
+	}
+}
+
+// WithLogger sends the breaker's logs to logger instead of slog.Default(). Pass
+// a logger with a discarding handler or a higher level to quiet them.
+func WithLogger(logger *slog.Logger) Option {
+	return func(cb *circuitBreaker) {
+		cb.logger = logger
+	}
+}
+
+// WithVerbose turns on the info logs written for every call, like "Making a
+// request". They're off by default since they flood the logs of a busy
+// breaker; state transitions, rejections and failures are logged either way.
+func WithVerbose(verbose bool) Option {
+	return func(cb *circuitBreaker) {
+		cb.verbose = verbose
+	}
+}
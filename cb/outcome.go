@@ -0,0 +1,44 @@
+package cb
+
+import "time"
+
+// Outcome is how a call through the breaker ended
+type Outcome string
+
+const (
+	OutcomeSuccess  Outcome = "success"  // The function ran and succeeded
+	OutcomeFailure  Outcome = "failure"  // The function ran and failed
+	OutcomeTimeout  Outcome = "timeout"  // The function didn't finish in time
+	OutcomeRejected Outcome = "rejected" // The function didn't run at all
+	OutcomeCanceled Outcome = "canceled" // The caller gave up on the call, which doesn't count either way
+)
+
+// FailureReason is why a call didn't get a result from the dependency, telling
+// a slow dependency from an erroring one at a glance
+type FailureReason string
+
+const (
+	ReasonError    FailureReason = "error"    // The function returned an error counted as a failure
+	ReasonTimeout  FailureReason = "timeout"  // The function didn't finish in time
+	ReasonRejected FailureReason = "rejected" // The breaker turned the call away
+)
+
+// failureReason returns why the admitted call failed
+func (c *call) failureReason() FailureReason {
+	if c.outcome == OutcomeTimeout {
+		return ReasonTimeout
+	}
+	return ReasonError
+}
+
+// WithOnCallComplete registers hook to be called after every call with how
+// long the protected function ran, how the call ended, and the error returned
+// to the caller. Rejected calls report a zero duration, and so do calls made
+// with a context that's already done, which report OutcomeCanceled. The hook
+// runs synchronously on the calling goroutine after the breaker's lock is
+// released.
+func WithOnCallComplete(hook func(d time.Duration, outcome Outcome, err error)) Option {
+	return func(cb *circuitBreaker) {
+		cb.onCallComplete = hook
+	}
+}
@@ -0,0 +1,113 @@
+package cb
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestCircuitBreaker_OnCallComplete(t *testing.T) {
+	t.Parallel()
+
+	type completion struct {
+		d       time.Duration
+		outcome Outcome
+		err     error
+	}
+
+	var completions []completion
+	clock := newFakeClock()
+	cb := NewCircuitBreaker(2, time.Minute, 1, 50*time.Millisecond, WithClock(clock), WithOnCallComplete(
+		func(d time.Duration, outcome Outcome, err error) {
+			completions = append(completions, completion{d, outcome, err})
+		},
+	))
+
+	_, _ = cb.Call(func() (any, error) {
+		clock.Advance(30 * time.Millisecond)
+		return 42, nil
+	})
+	_, _ = cb.Call(func() (any, error) {
+		clock.Advance(10 * time.Millisecond)
+		return nil, errFailure
+	})
+	_, _ = cb.Call(func() (any, error) {
+		time.Sleep(100 * time.Millisecond)
+		return 42, nil
+	})
+	_, _ = cb.Call(func() (any, error) {
+		return 42, nil
+	})
+
+	want := []completion{
+		{30 * time.Millisecond, OutcomeSuccess, nil},
+		{10 * time.Millisecond, OutcomeFailure, errFailure},
+		{0, OutcomeTimeout, nil}, // The fake clock doesn't move while sleeping
+		{0, OutcomeRejected, ErrCircuitOpen},
+	}
+	if len(completions) != len(want) {
+		t.Fatalf("expected %d completions, got %v", len(want), completions)
+	}
+
+	for i, got := range completions {
+		w := want[i]
+		if got.d != w.d || got.outcome != w.outcome {
+			t.Fatalf("completion %d: expected %v %s, got %v %s", i, w.d, w.outcome, got.d, got.outcome)
+		}
+		if w.err != nil && !errors.Is(got.err, w.err) {
+			t.Fatalf("completion %d: expected error %v, got %v", i, w.err, got.err)
+		}
+	}
+
+	if completions[2].err == nil {
+		t.Fatalf("expected the timeout to report its error")
+	}
+}
+
+func TestCircuitBreaker_OnCallCompleteOutsideLock(t *testing.T) {
+	t.Parallel()
+
+	var cb *circuitBreaker
+	var states []State
+	cb = NewCircuitBreaker(1, time.Minute, 1, time.Second, WithOnCallComplete(
+		func(time.Duration, Outcome, error) {
+			// Would deadlock if the hook ran under the lock
+			states = append(states, cb.Stats().State)
+		},
+	))
+
+	_, _ = cb.Call(func() (any, error) {
+		return nil, errFailure
+	})
+
+	if len(states) != 1 || states[0] != Open {
+		t.Fatalf("expected the hook to observe the open state, got %v", states)
+	}
+}
+
+func TestCircuitBreaker_OnCallCompleteCanceledContext(t *testing.T) {
+	t.Parallel()
+
+	var outcomes []Outcome
+	var errs []error
+	cb := NewCircuitBreaker(1, time.Minute, 1, time.Second, WithOnCallComplete(
+		func(d time.Duration, outcome Outcome, err error) {
+			outcomes = append(outcomes, outcome)
+			errs = append(errs, err)
+		},
+	))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	_, err := cb.CallContext(ctx, func(context.Context) (any, error) {
+		return 42, nil
+	})
+	if !errors.Is(err, context.Canceled) {
+		t.Fatalf("expected context.Canceled, got %v", err)
+	}
+
+	if len(outcomes) != 1 || outcomes[0] != OutcomeCanceled || !errors.Is(errs[0], context.Canceled) {
+		t.Fatalf("expected the hook to see the call canceled, got %v %v", outcomes, errs)
+	}
+}
@@ -0,0 +1,58 @@
+package cb
+
+import "time"
+
+// Reset forces the breaker back to closed and forgets everything it counted,
+// including any recovery backoff, as if it were new. Unlike automatic
+// transitions, forced ones bypass the transition guard. State change hooks
+// fire if the breaker wasn't closed already.
+func (cb *circuitBreaker) Reset() {
+	cb.mu.Lock()
+	if cb.state != Closed {
+		cb.transition(Closed)
+	}
+	cb.clearCounts()
+	cb.reopens = 0
+	cb.generation++ // Calls in flight belong to the forgotten history
+	cb.log().Info("Circuit manually reset")
+	cb.unlock()
+}
+
+// Trip forces the breaker open, e.g. to drain a bad node, and restarts its
+// recovery timer. The breaker then recovers on its own once the recovery time
+// passes, like after an automatic trip.
+func (cb *circuitBreaker) Trip() {
+	cb.mu.Lock()
+	if cb.state != Open {
+		cb.transition(Open)
+	}
+	cb.startRecovery()
+	cb.log().Warn("Circuit manually tripped")
+	cb.unlock()
+}
+
+// TripUntil forces the breaker open like Trip, but recovers at deadline rather
+// than after the recovery time, so that a fleet of breakers can be made to
+// probe at the same moment
+func (cb *circuitBreaker) TripUntil(deadline time.Time) {
+	cb.mu.Lock()
+	if cb.state != Open {
+		cb.transition(Open)
+	}
+	cb.lastFailureTime = cb.clock.Now()
+	cb.openUntil = deadline
+	cb.publishOpen()
+	cb.log().Warn("Circuit manually tripped", "until", deadline)
+	cb.unlock()
+}
+
+// Close forces the breaker closed, e.g. after a known fix, without waiting
+// for half-open probes. Unlike Reset it's a no-op on a closed breaker.
+func (cb *circuitBreaker) Close() {
+	cb.mu.Lock()
+	if cb.state != Closed {
+		cb.transition(Closed)
+		cb.log().Info("Circuit manually closed")
+	}
+	cb.unlock()
+}
@@ -0,0 +1,116 @@
+package cb
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestCircuitBreaker_Trip(t *testing.T) {
+	t.Parallel()
+
+	clock := newFakeClock()
+	var transitions []string
+	cb := NewCircuitBreaker(5, time.Second, 1, time.Second, WithClock(clock), WithOnStateChange(func(from, to State) {
+		transitions = append(transitions, from.String()+"->"+to.String())
+	}))
+
+	cb.Trip()
+	if got := cb.State(); got != Open {
+		t.Fatalf("expected state open, got %s", got)
+	}
+
+	// The forced trip still recovers once the recovery time passes
+	clock.Advance(2 * time.Second)
+	_, _ = cb.Call(func() (any, error) {
+		return 42, nil
+	})
+	if got := cb.State(); got != Closed {
+		t.Fatalf("expected the probe after the recovery time to close the circuit, got %s", got)
+	}
+
+	if len(transitions) != 3 || transitions[0] != "closed->open" {
+		t.Fatalf("expected the forced trip to fire the hook, got %v", transitions)
+	}
+}
+
+func TestCircuitBreaker_TripRestartsRecoveryTimer(t *testing.T) {
+	t.Parallel()
+
+	clock := newFakeClock()
+	cb := NewCircuitBreaker(1, 10*time.Second, 1, time.Second, WithClock(clock))
+
+	_, _ = cb.Call(func() (any, error) {
+		return nil, errFailure
+	})
+	clock.Advance(9 * time.Second)
+	cb.Trip()
+	clock.Advance(2 * time.Second)
+
+	_, err := cb.Call(func() (any, error) {
+		return 42, nil
+	})
+	if !errors.Is(err, ErrCircuitOpen) {
+		t.Fatalf("expected the trip to restart the recovery timer, got %v", err)
+	}
+}
+
+func TestCircuitBreaker_TripUntil(t *testing.T) {
+	t.Parallel()
+
+	clock := newFakeClock()
+	cb := NewCircuitBreaker(1, 10*time.Second, 1, time.Second, WithClock(clock))
+	deadline := clock.Now().Add(time.Minute)
+	cb.TripUntil(deadline)
+
+	if got := cb.Stats().OpenUntil; !got.Equal(deadline) {
+		t.Fatalf("expected the circuit open until %v, got %v", deadline, got)
+	}
+
+	clock.Advance(30 * time.Second)
+	_, err := cb.Call(func() (any, error) {
+		return 42, nil
+	})
+	var openErr *OpenError
+	if !errors.As(err, &openErr) || openErr.RetryAfter() != 30*time.Second {
+		t.Fatalf("expected to be told to retry in 30s, got %v", err)
+	}
+
+	clock.Advance(30*time.Second + time.Millisecond)
+	if _, err := cb.Call(func() (any, error) {
+		return 42, nil
+	}); err != nil {
+		t.Fatalf("expected the circuit to recover at the deadline, got %v", err)
+	}
+}
+
+func TestCircuitBreaker_CloseAndReset(t *testing.T) {
+	t.Parallel()
+
+	cb := NewCircuitBreaker(3, time.Minute, 1, time.Second, WithTransitionGuard(func(from, to State) bool {
+		return false // Vetoes every automatic transition
+	}))
+
+	cb.Trip()
+	if got := cb.State(); got != Open {
+		t.Fatalf("expected the forced trip to bypass the guard, got %s", got)
+	}
+
+	cb.Close()
+	if got := cb.State(); got != Closed {
+		t.Fatalf("expected the forced close to bypass the guard, got %s", got)
+	}
+
+	_, _ = cb.Call(func() (any, error) {
+		return nil, errFailure
+	})
+	cb.Close()
+	if got := cb.Counts().ConsecutiveFailures; got != 1 {
+		t.Fatalf("expected closing a closed breaker to keep its counts, got %d failures", got)
+	}
+
+	cb.Reset()
+	if got := cb.Counts(); got != (Counts{}) {
+		t.Fatalf("expected reset to clear the counts, got %+v", got)
+	}
+}
@@ -0,0 +1,101 @@
+package cb
+
+import "time"
+
+// WithSingleProbe makes the half-open circuit admit one probe at a time,
+// rejecting every other caller with ErrHalfOpenBudgetExceeded, which is an
+// ErrTooManyRequests, until that probe resolves. Probes still stop at the
+// half-open max requests, but they run one after the other rather than all at
+// once, so a dependency that's barely back up only ever sees one of them.
+func WithSingleProbe() Option {
+	return func(cb *circuitBreaker) {
+		cb.singleProbe = true
+	}
+}
+
+// probeInFlight reports whether a single-probe circuit has to wait for its
+// probe to resolve before admitting another
+func (cb *circuitBreaker) probeInFlight() bool {
+	return cb.singleProbe && cb.probing
+}
+
+// WithHalfOpenProbeInterval spaces the half-open circuit's probes at least
+// interval apart, rejecting callers that arrive sooner after the last probe
+// with ErrHalfOpenBudgetExceeded, so that bursty traffic can't spend the whole
+// probe budget at once on a dependency that's barely back up.
+func WithHalfOpenProbeInterval(interval time.Duration) Option {
+	return func(cb *circuitBreaker) {
+		cb.probeInterval = interval
+	}
+}
+
+// probeTooSoon reports whether the half-open circuit admitted its last probe
+// less than the probe interval ago
+func (cb *circuitBreaker) probeTooSoon() bool {
+	return cb.probeInterval > 0 && !cb.lastProbe.IsZero() && cb.clock.Now().Sub(cb.lastProbe) < cb.probeInterval
+}
+
+// WithHalfOpenFailureTolerance lets the half-open circuit absorb up to n
+// consecutive failed probes before reopening, so a single blip doesn't erase
+// the successful probes before it: successes keep counting toward closing
+// across tolerated failures, which don't use up the probe budget unless
+// WithHalfOpenWindow makes it strict. Defaults to zero, reopening on the first
+// failure.
+func WithHalfOpenFailureTolerance(n int) Option {
+	return func(cb *circuitBreaker) {
+		cb.probeTolerance = n
+	}
+}
+
+// probesUsed returns how much of the half-open probe budget is spent, leaving
+// out tolerated failures
+func (cb *circuitBreaker) probesUsed() int {
+	if cb.probeTolerance > 0 && cb.halfOpenWindow == 0 {
+		return cb.counts.Requests - cb.counts.TotalFailures
+	}
+	return cb.counts.Requests
+}
+
+// tolerateProbeFailure counts a failed probe without reopening the circuit if
+// the failure tolerance allows it, keeping the successes seen so far
+func (cb *circuitBreaker) tolerateProbeFailure() bool {
+	if cb.counts.ConsecutiveFailures >= cb.probeTolerance {
+		return false
+	}
+	cb.counts.TotalFailures++
+	cb.counts.ConsecutiveFailures++
+	return true
+}
+
+// WithHalfOpenWindow makes the half-open probe budget a strict cap: each
+// half-open episode admits at most the half-open max requests in total, and
+// the circuit reopens if it hasn't closed within window of going half-open,
+// so probes can't trickle in indefinitely. The circuit closes once the
+// successes to close are in, which with WithSuccessesToClose may be before
+// every admitted probe has finished; with the default, every probe has to
+// succeed within the window. Tolerated failures still use up the budget.
+func WithHalfOpenWindow(window time.Duration) Option {
+	return func(cb *circuitBreaker) {
+		cb.halfOpenWindow = window
+	}
+}
+
+// halfOpenExpired reports whether the half-open circuit ran out of time to
+// close
+func (cb *circuitBreaker) halfOpenExpired() bool {
+	return cb.halfOpenWindow > 0 && cb.clock.Now().Sub(cb.halfOpenSince) > cb.halfOpenWindow
+}
+
+// WithShouldProbe lets shouldProbe decide whether the half-open circuit admits
+// the current request as a probe, for adaptive strategies such as probing only
+// while traffic is low. Requests it turns down are rejected with
+// ErrHalfOpenBudgetExceeded, like any other request the half-open circuit
+// doesn't admit, so fallbacks and rejection responses apply to them. It's
+// consulted only while the probe budget lasts, so the half-open max requests
+// still caps the probes it admits, and it's called with the breaker's lock
+// held, so it mustn't call back into the breaker.
+func WithShouldProbe(shouldProbe func() bool) Option {
+	return func(cb *circuitBreaker) {
+		cb.shouldProbe = shouldProbe
+	}
+}
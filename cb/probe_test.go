@@ -0,0 +1,295 @@
+package cb
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestCircuitBreaker_SingleProbe(t *testing.T) {
+	t.Parallel()
+
+	cb := NewCircuitBreaker(1, time.Minute, 2, time.Second, WithSingleProbe())
+	cb.setState(HalfOpen)
+
+	started, release := make(chan struct{}), make(chan struct{})
+	done := make(chan error, 1)
+	go func() {
+		_, err := cb.Call(func() (any, error) {
+			close(started)
+			<-release
+			return 42, nil
+		})
+		done <- err
+	}()
+	<-started
+
+	_, err := cb.Call(func() (any, error) {
+		return 42, nil
+	})
+	if !errors.Is(err, ErrTooManyRequests) {
+		t.Fatalf("expected ErrTooManyRequests while the probe is in flight, got %v", err)
+	}
+
+	close(release)
+	if err := <-done; err != nil {
+		t.Fatalf("expected the probe to succeed, got %v", err)
+	}
+	if cb.State() != HalfOpen {
+		t.Fatalf("expected state half-open after 1 of 2 probes, got %s", cb.State())
+	}
+
+	// The next probe is admitted once the first has resolved
+	_, err = cb.Call(func() (any, error) {
+		return 42, nil
+	})
+	if err != nil {
+		t.Fatalf("expected the second probe to be admitted, got %v", err)
+	}
+	if cb.State() != Closed {
+		t.Fatalf("expected state closed, got %s", cb.State())
+	}
+}
+
+func TestCircuitBreaker_SingleProbeTimeoutFreesTheSlot(t *testing.T) {
+	t.Parallel()
+
+	cb := NewCircuitBreaker(1, time.Minute, 2, 20*time.Millisecond, WithSingleProbe(), WithFailureThreshold(1))
+	cb.setState(HalfOpen)
+
+	_, err := cb.Call(func() (any, error) {
+		time.Sleep(100 * time.Millisecond)
+		return 42, nil
+	})
+	if !errors.Is(err, ErrTimeout) {
+		t.Fatalf("expected ErrTimeout, got %v", err)
+	}
+	if cb.State() != Open {
+		t.Fatalf("expected the timed-out probe to reopen the circuit, got %s", cb.State())
+	}
+
+	cb.setState(HalfOpen)
+	if _, err := cb.Call(func() (any, error) { return 42, nil }); err != nil {
+		t.Fatalf("expected a fresh half-open episode to admit a probe, got %v", err)
+	}
+}
+
+func TestCircuitBreaker_HalfOpenProbeInterval(t *testing.T) {
+	t.Parallel()
+
+	clock := newFakeClock()
+	cb := NewCircuitBreaker(1, time.Minute, 3, time.Second, WithClock(clock), WithHalfOpenProbeInterval(time.Second))
+	cb.setState(HalfOpen)
+	successFn := func() (any, error) {
+		return 42, nil
+	}
+
+	if _, err := cb.Call(successFn); err != nil {
+		t.Fatalf("expected the first probe to be admitted, got %v", err)
+	}
+	for i := 0; i < 3; i++ {
+		if _, err := cb.Call(successFn); !errors.Is(err, ErrHalfOpenBudgetExceeded) {
+			t.Fatalf("expected back-to-back probes to be rejected, got %v", err)
+		}
+	}
+
+	clock.Advance(time.Second)
+	if _, err := cb.Call(successFn); err != nil {
+		t.Fatalf("expected a probe once the interval passed, got %v", err)
+	}
+	if got := cb.Counts().ConsecutiveSuccesses; got != 2 || cb.State() != HalfOpen {
+		t.Fatalf("expected 2 of 3 probes in, got %d in state %s", got, cb.State())
+	}
+}
+
+func TestCircuitBreaker_HalfOpenFailureTolerance(t *testing.T) {
+	t.Parallel()
+
+	cb := NewCircuitBreaker(1, time.Minute, 3, time.Second, WithHalfOpenFailureTolerance(1))
+	cb.setState(HalfOpen)
+	succeed := func() (any, error) {
+		return 42, nil
+	}
+	fail := func() (any, error) {
+		return nil, errFailure
+	}
+
+	_, _ = cb.Call(succeed)
+	_, _ = cb.Call(fail)
+	if cb.State() != HalfOpen {
+		t.Fatalf("expected a tolerated failure to keep the circuit half-open, got %s", cb.State())
+	}
+	if got := cb.Counts().ConsecutiveSuccesses; got != 1 {
+		t.Fatalf("expected the tolerated failure to keep 1 success, got %d", got)
+	}
+
+	_, _ = cb.Call(succeed)
+	if cb.State() != HalfOpen {
+		t.Fatalf("expected state half-open after 2 of 3 successes, got %s", cb.State())
+	}
+
+	// The tolerated failure gave its probe back to the budget
+	if _, err := cb.Call(succeed); err != nil {
+		t.Fatalf("expected the third success to be admitted, got %v", err)
+	}
+	if cb.State() != Closed {
+		t.Fatalf("expected state closed, got %s", cb.State())
+	}
+}
+
+func TestCircuitBreaker_HalfOpenFailureToleranceExceeded(t *testing.T) {
+	t.Parallel()
+
+	cb := NewCircuitBreaker(1, time.Minute, 3, time.Second, WithHalfOpenFailureTolerance(1))
+	cb.setState(HalfOpen)
+	fail := func() (any, error) {
+		return nil, errFailure
+	}
+
+	_, _ = cb.Call(fail)
+	if cb.State() != HalfOpen {
+		t.Fatalf("expected the first failure to be tolerated, got %s", cb.State())
+	}
+	_, _ = cb.Call(fail)
+	if cb.State() != Open {
+		t.Fatalf("expected a second failure in a row to reopen the circuit, got %s", cb.State())
+	}
+}
+
+func TestCircuitBreaker_HalfOpenStrictByDefault(t *testing.T) {
+	t.Parallel()
+
+	cb := NewCircuitBreaker(1, time.Minute, 3, time.Second)
+	cb.setState(HalfOpen)
+
+	_, _ = cb.Call(func() (any, error) {
+		return 42, nil
+	})
+	_, _ = cb.Call(func() (any, error) {
+		return nil, errFailure
+	})
+	if cb.State() != Open {
+		t.Fatalf("expected a failed probe to reopen the circuit, got %s", cb.State())
+	}
+}
+
+func TestCircuitBreaker_HalfOpenWindow(t *testing.T) {
+	t.Parallel()
+
+	clock := newFakeClock()
+	cb := NewCircuitBreaker(1, time.Minute, 2, time.Second, WithClock(clock), WithHalfOpenWindow(10*time.Second))
+	cb.setState(HalfOpen)
+
+	if _, err := cb.Call(func() (any, error) { return 42, nil }); err != nil {
+		t.Fatalf("expected the first probe to be admitted, got %v", err)
+	}
+	clock.Advance(11 * time.Second)
+
+	ran := false
+	_, err := cb.Call(func() (any, error) {
+		ran = true
+		return 42, nil
+	})
+	if !errors.Is(err, ErrCircuitOpen) || ran {
+		t.Fatalf("expected a probe after the window to be rejected, got %v", err)
+	}
+	if cb.State() != Open {
+		t.Fatalf("expected the circuit to reopen after the window, got %s", cb.State())
+	}
+}
+
+func TestCircuitBreaker_HalfOpenWindowLateProbe(t *testing.T) {
+	t.Parallel()
+
+	clock := newFakeClock()
+	cb := NewCircuitBreaker(1, time.Minute, 1, time.Second, WithClock(clock), WithHalfOpenWindow(10*time.Second))
+	cb.setState(HalfOpen)
+
+	result, err := cb.Call(func() (any, error) {
+		clock.Advance(11 * time.Second)
+		return 42, nil
+	})
+	if err != nil || result != 42 {
+		t.Fatalf("expected the late probe to return its result, got %v, %v", result, err)
+	}
+	if cb.State() != Open {
+		t.Fatalf("expected a probe finishing after the window to reopen the circuit, got %s", cb.State())
+	}
+}
+
+func TestCircuitBreaker_HalfOpenWindowStrictBudget(t *testing.T) {
+	t.Parallel()
+
+	clock := newFakeClock()
+	cb := NewCircuitBreaker(1, time.Minute, 2, time.Second, WithClock(clock),
+		WithHalfOpenWindow(10*time.Second), WithHalfOpenFailureTolerance(1))
+	cb.setState(HalfOpen)
+
+	_, _ = cb.Call(func() (any, error) { return nil, errFailure })
+	_, _ = cb.Call(func() (any, error) { return 42, nil })
+
+	_, err := cb.Call(func() (any, error) { return 42, nil })
+	if !errors.Is(err, ErrHalfOpenBudgetExceeded) {
+		t.Fatalf("expected the tolerated failure to use up the strict budget, got %v", err)
+	}
+	if cb.State() != HalfOpen {
+		t.Fatalf("expected state half-open, got %s", cb.State())
+	}
+
+	clock.Advance(11 * time.Second)
+	_, _ = cb.Call(func() (any, error) { return 42, nil })
+	if cb.State() != Open {
+		t.Fatalf("expected the circuit to reopen once the window is over, got %s", cb.State())
+	}
+}
+
+func TestCircuitBreaker_ShouldProbe(t *testing.T) {
+	t.Parallel()
+
+	allow := false
+	asked := 0
+	cb := NewCircuitBreaker(1, time.Minute, 2, time.Second, WithShouldProbe(func() bool {
+		asked++
+		return allow
+	}))
+	cb.setState(HalfOpen)
+	succeed := func() (any, error) {
+		return 42, nil
+	}
+
+	if _, err := cb.Call(succeed); !errors.Is(err, ErrHalfOpenBudgetExceeded) {
+		t.Fatalf("expected ErrHalfOpenBudgetExceeded when the callback declines, got %v", err)
+	}
+
+	allow = true
+	_, _ = cb.Call(succeed)
+	if cb.State() != HalfOpen {
+		t.Fatalf("expected state half-open after 1 of 2 probes, got %s", cb.State())
+	}
+	_, _ = cb.Call(succeed)
+	if cb.State() != Closed {
+		t.Fatalf("expected state closed, got %s", cb.State())
+	}
+	if asked != 3 {
+		t.Fatalf("expected the callback to be asked 3 times, got %d", asked)
+	}
+}
+
+func TestCircuitBreaker_ShouldProbeWithinBudget(t *testing.T) {
+	t.Parallel()
+
+	asked := 0
+	cb := NewCircuitBreaker(1, time.Minute, 1, time.Second, WithShouldProbe(func() bool {
+		asked++
+		return true
+	}))
+	cb.setState(HalfOpen)
+	cb.counts.Requests = 1 // The only probe already admitted
+
+	if _, err := cb.Call(func() (any, error) { return 42, nil }); !errors.Is(err, ErrHalfOpenBudgetExceeded) {
+		t.Fatalf("expected the budget to still cap probes, got %v", err)
+	}
+	if asked != 0 {
+		t.Fatalf("expected the callback not to be asked once the budget is spent, got %d", asked)
+	}
+}
@@ -0,0 +1,41 @@
+package cb
+
+import (
+	"math/rand"
+	"sync"
+	"time"
+)
+
+// lockedRand is a random number generator that's safe for concurrent use.
+// Every randomized behavior of the breaker draws from the same one so that a
+// single seed makes them all reproducible.
+type lockedRand struct {
+	mu sync.Mutex
+	r  *rand.Rand
+}
+
+// newLockedRand creates a generator backed by src
+func newLockedRand(src rand.Source) *lockedRand {
+	return &lockedRand{r: rand.New(src)}
+}
+
+// newTimeSeededRand creates a generator seeded from the current time
+func newTimeSeededRand() *lockedRand {
+	return newLockedRand(rand.NewSource(time.Now().UnixNano()))
+}
+
+// Float64 returns a pseudo-random number in [0.0, 1.0)
+func (l *lockedRand) Float64() float64 {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	return l.r.Float64()
+}
+
+// Int63n returns a non-negative pseudo-random number in [0, n)
+func (l *lockedRand) Int63n(n int64) int64 {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	return l.r.Int63n(n)
+}
@@ -0,0 +1,65 @@
+package cb
+
+import (
+	"errors"
+	"math/rand"
+	"testing"
+	"time"
+)
+
+func TestCircuitBreaker_RandSourceDeterministic(t *testing.T) {
+	t.Parallel()
+
+	a := NewCircuitBreaker(1, time.Second, 1, time.Second, WithRandSource(rand.NewSource(7)))
+	b := NewCircuitBreaker(1, time.Second, 1, time.Second, WithRandSource(rand.NewSource(7)))
+
+	for i := 0; i < 100; i++ {
+		if x, y := a.rand.Float64(), b.rand.Float64(); x != y {
+			t.Fatalf("expected identical sequences, diverged at %d: %v != %v", i, x, y)
+		}
+	}
+
+	for i := 0; i < 100; i++ {
+		if x, y := a.rand.Int63n(1000), b.rand.Int63n(1000); x != y {
+			t.Fatalf("expected identical sequences, diverged at %d: %v != %v", i, x, y)
+		}
+	}
+}
+
+func TestCircuitBreaker_RandSourceSeedsJitter(t *testing.T) {
+	t.Parallel()
+
+	const recovery, jitter = 10 * time.Second, 5 * time.Second
+	clock := newFakeClock()
+	cb := NewCircuitBreaker(1, recovery, 1, time.Second, WithClock(clock), WithRecoveryJitter(jitter), WithRandSource(rand.NewSource(7)))
+
+	// The same seed replays the jitter drawn on every trip
+	want := rand.New(rand.NewSource(7))
+	for i := 0; i < 10; i++ {
+		cb.Trip()
+		_, err := cb.Call(func() (any, error) {
+			return 42, nil
+		})
+
+		var openErr *OpenError
+		expected := recovery + time.Duration(want.Int63n(int64(jitter)+1))
+		if !errors.As(err, &openErr) || openErr.RetryAfter() != expected {
+			t.Fatalf("expected trip %d to retry after %s, got %v", i, expected, err)
+		}
+		cb.Reset()
+	}
+}
+
+func TestCircuitBreaker_DefaultRandSource(t *testing.T) {
+	t.Parallel()
+
+	cb := NewCircuitBreaker(1, time.Second, 1, time.Second)
+
+	if cb.rand == nil {
+		t.Fatalf("expected a default random source")
+	}
+
+	if f := cb.rand.Float64(); f < 0 || f >= 1 {
+		t.Fatalf("expected a value in [0, 1), got %v", f)
+	}
+}
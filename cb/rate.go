@@ -0,0 +1,62 @@
+package cb
+
+// failureRate tracks the outcomes of the most recent requests so that the
+// circuit can trip on the share of them that failed
+type failureRate struct {
+	threshold       float64 // Failure ratio that trips the circuit
+	minimumRequests int     // Requests needed before the ratio is trusted
+	outcomes        []bool  // Ring of recent outcomes, true for a failure
+	next            int     // Index the next outcome is written to
+	total           int     // Number of outcomes in the ring
+	failures        int     // Number of failures in the ring
+}
+
+// WithFailureRate trips the circuit when at least threshold of the last
+// window requests failed, e.g. 0.5 over 20, instead of after a number of
+// consecutive failures. The circuit never trips on fewer than minimumRequests
+// requests, so that one bad call out of two doesn't open it. A window or
+// threshold of zero or less restores consecutive counting.
+func WithFailureRate(threshold float64, minimumRequests, window int) Option {
+	return func(cb *circuitBreaker) {
+		cb.rate = nil
+		if window > 0 && threshold > 0 {
+			cb.rate = &failureRate{
+				threshold:       threshold,
+				minimumRequests: min(max(minimumRequests, 1), window),
+				outcomes:        make([]bool, window),
+			}
+		}
+	}
+}
+
+// record adds the outcome of a request, evicting the oldest once full
+func (r *failureRate) record(failed bool) {
+	if r.total == len(r.outcomes) {
+		if r.outcomes[r.next] {
+			r.failures--
+		}
+	} else {
+		r.total++
+	}
+
+	r.outcomes[r.next] = failed
+	if failed {
+		r.failures++
+	}
+	r.next = (r.next + 1) % len(r.outcomes)
+}
+
+// exceeded reports whether enough requests were seen and the share of them
+// that failed reached the threshold
+func (r *failureRate) exceeded() bool {
+	if r.total < r.minimumRequests {
+		return false
+	}
+	return float64(r.failures)/float64(r.total) >= r.threshold
+}
+
+// clear forgets every outcome
+func (r *failureRate) clear() {
+	clear(r.outcomes)
+	r.next, r.total, r.failures = 0, 0, 0
+}
@@ -0,0 +1,103 @@
+package cb
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestCircuitBreaker_FailureRateTrips(t *testing.T) {
+	t.Parallel()
+
+	cb := NewCircuitBreaker(100, time.Minute, 1, time.Second, WithFailureRate(0.5, 4, 10))
+
+	failFn := func() (any, error) {
+		return nil, errFailure
+	}
+	successFn := func() (any, error) {
+		return 42, nil
+	}
+
+	// Alternating outcomes never make two failures consecutive
+	_, _ = cb.Call(successFn)
+	_, _ = cb.Call(failFn)
+	_, _ = cb.Call(successFn)
+	if cb.state != Closed {
+		t.Fatalf("expected state closed below the minimum volume, got %s", cb.state)
+	}
+
+	_, _ = cb.Call(failFn)
+	if cb.state != Open {
+		t.Fatalf("expected state open at a 50%% failure rate, got %s", cb.state)
+	}
+}
+
+func TestCircuitBreaker_FailureRateMinimumRequests(t *testing.T) {
+	t.Parallel()
+
+	cb := NewCircuitBreaker(1, time.Minute, 1, time.Second, WithFailureRate(0.5, 3, 20))
+
+	failFn := func() (any, error) {
+		return nil, errFailure
+	}
+
+	// One bad call out of one, then two out of two, don't trip on their own
+	_, _ = cb.Call(failFn)
+	_, _ = cb.Call(failFn)
+	if cb.state != Closed {
+		t.Fatalf("expected state closed below the minimum volume, got %s", cb.state)
+	}
+
+	_, _ = cb.Call(failFn)
+	if cb.state != Open {
+		t.Fatalf("expected state open once the minimum volume is reached, got %s", cb.state)
+	}
+}
+
+func TestCircuitBreaker_FailureRateEvictsOldOutcomes(t *testing.T) {
+	t.Parallel()
+
+	cb := NewCircuitBreaker(1, time.Minute, 1, time.Second, WithFailureRate(0.5, 4, 4))
+
+	failFn := func() (any, error) {
+		return nil, errFailure
+	}
+	successFn := func() (any, error) {
+		return 42, nil
+	}
+
+	// Fill the window with one failure, then push it out with successes
+	_, _ = cb.Call(failFn)
+	for i := 0; i < 4; i++ {
+		_, _ = cb.Call(successFn)
+	}
+	if got := cb.rate.failures; got != 0 {
+		t.Fatalf("expected the failure to be evicted, got %d failures", got)
+	}
+
+	_, _ = cb.Call(failFn)
+	if cb.state != Closed {
+		t.Fatalf("expected state closed at a 25%% failure rate, got %s", cb.state)
+	}
+	_, _ = cb.Call(failFn)
+	if cb.state != Open {
+		t.Fatalf("expected state open at a 50%% failure rate, got %s", cb.state)
+	}
+}
+
+func TestCircuitBreaker_FailureRateDescribe(t *testing.T) {
+	t.Parallel()
+
+	cb := NewCircuitBreaker(1, time.Minute, 1, time.Second, WithFailureRate(0.5, 10, 20))
+
+	out := cb.Describe()
+	for _, want := range []string{
+		"mode: failure rate",
+		"failure rate: 0.5 over 20 requests, at least 10",
+		"closed -> open: once 0.5 of the last 20 requests failed",
+	} {
+		if !strings.Contains(out, want) {
+			t.Fatalf("expected description to contain %q, got:\n%s", want, out)
+		}
+	}
+}
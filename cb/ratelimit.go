@@ -0,0 +1,44 @@
+package cb
+
+import "time"
+
+// rateLimit is a limit of n requests per interval
+type rateLimit struct {
+	n        int           // Requests allowed per interval
+	interval time.Duration // Period the limit applies to
+}
+
+// tokenBucket is a token bucket rate limiter that refills continuously
+type tokenBucket struct {
+	capacity float64       // Maximum number of tokens the bucket holds
+	interval time.Duration // Time to refill the bucket from empty
+	rate     float64       // Number of tokens added per second
+	tokens   float64       // Number of tokens currently available
+	last     time.Time     // Time of the last refill
+}
+
+// newTokenBucket creates a full bucket allowing n requests per interval
+func newTokenBucket(n int, interval time.Duration) *tokenBucket {
+	return &tokenBucket{
+		capacity: float64(n),
+		interval: interval,
+		rate:     float64(n) / interval.Seconds(),
+		tokens:   float64(n),
+	}
+}
+
+// allow refills the bucket up to now and takes a token if one is available
+func (b *tokenBucket) allow(now time.Time) bool {
+	if !b.last.IsZero() {
+		elapsed := now.Sub(b.last).Seconds()
+		b.tokens = min(b.capacity, b.tokens+elapsed*b.rate)
+	}
+	b.last = now
+
+	if b.tokens < 1 {
+		return false
+	}
+
+	b.tokens--
+	return true
+}
@@ -0,0 +1,97 @@
+package cb
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestCircuitBreaker_RequestRateLimit(t *testing.T) {
+	t.Parallel()
+
+	clock := newFakeClock()
+	cb := NewCircuitBreaker(
+		3, 5*time.Second, 1, 2*time.Second,
+		WithClock(clock),
+		WithRequestRateLimit(3, time.Second),
+	)
+
+	successFn := func() (any, error) {
+		return 42, nil
+	}
+
+	for i := 0; i < 3; i++ {
+		if _, err := cb.Call(successFn); err != nil {
+			t.Fatalf("expected request %d to be allowed, got %v", i+1, err)
+		}
+	}
+
+	// The fourth request within the same interval exceeds the limit
+	_, err := cb.Call(successFn)
+	if !errors.Is(err, ErrRateLimited) {
+		t.Fatalf("expected ErrRateLimited, got %v", err)
+	}
+
+	if cb.state != Closed || cb.counts.TotalFailures != 0 {
+		t.Fatalf("expected rate limited requests not to count as failures, got %s %+v", cb.state, cb.counts)
+	}
+	if got := cb.Stats(); got.Throttled != 1 || got.Rejections != 1 {
+		t.Fatalf("expected 1 throttled rejection, got %d throttled and %d rejections", got.Throttled, got.Rejections)
+	}
+
+	// A full interval later the bucket has refilled
+	clock.Advance(time.Second)
+
+	if _, err := cb.Call(successFn); err != nil {
+		t.Fatalf("expected request to be allowed after the interval, got %v", err)
+	}
+}
+
+func TestTokenBucket_PartialRefill(t *testing.T) {
+	t.Parallel()
+
+	now := time.Now()
+	b := newTokenBucket(2, time.Second)
+
+	if !b.allow(now) || !b.allow(now) {
+		t.Fatalf("expected the initial burst to be allowed")
+	}
+
+	if b.allow(now) {
+		t.Fatalf("expected an empty bucket to reject")
+	}
+
+	// Half an interval refills one of the two tokens
+	now = now.Add(500 * time.Millisecond)
+	if !b.allow(now) {
+		t.Fatalf("expected one token after half an interval")
+	}
+
+	if b.allow(now) {
+		t.Fatalf("expected the bucket to be empty again")
+	}
+}
+
+func TestCircuitBreaker_RequestRateLimitNonPositive(t *testing.T) {
+	t.Parallel()
+
+	tests := map[string]struct {
+		n        int
+		interval time.Duration
+	}{
+		"zero requests":     {0, time.Second},
+		"negative requests": {-1, time.Second},
+		"zero interval":     {10, 0},
+		"negative interval": {10, -time.Second},
+	}
+
+	for name, tt := range tests {
+		cb := NewCircuitBreaker(3, 5*time.Second, 1, 2*time.Second, WithRequestRateLimit(tt.n, tt.interval))
+
+		for i := 0; i < 20; i++ {
+			if _, err := cb.Call(func() (any, error) { return 42, nil }); err != nil {
+				t.Fatalf("%s: expected no limit, got %v", name, err)
+			}
+		}
+	}
+}
@@ -0,0 +1,109 @@
+package cb
+
+import (
+	"maps"
+	"slices"
+	"sync"
+)
+
+// Registry holds one lazily created circuit breaker per name, sharing the
+// configuration of a single factory
+type Registry struct {
+	mu         sync.Mutex                          // Guards the fields below
+	newBreaker func() *circuitBreaker              // Creates a breaker with the shared configuration
+	breakers   map[string]*circuitBreaker          // Breakers created so far, by name
+	hooks      []func(name string, from, to State) // Notified of every breaker's transitions
+}
+
+// Manager is another name for Registry, for code that thinks of it as the
+// manager of an application's breakers
+type Manager = Registry
+
+// NewRegistry creates an empty registry whose breakers are built by newBreaker
+func NewRegistry(newBreaker func() *circuitBreaker) *Registry {
+	return &Registry{
+		newBreaker: newBreaker,
+		breakers:   make(map[string]*circuitBreaker),
+	}
+}
+
+// NewManager creates an empty registry whose breakers are all built by New
+// with the shared default opts
+func NewManager(opts ...Option) *Manager {
+	return NewRegistry(func() *circuitBreaker {
+		return New(opts...)
+	})
+}
+
+// Get returns the breaker registered under name, creating it on first use.
+// A breaker created without a name of its own takes on name.
+func (r *Registry) Get(name string) *circuitBreaker {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if cb, ok := r.breakers[name]; ok {
+		return cb
+	}
+
+	cb := r.newBreaker()
+	if cb.name == "" {
+		cb.name = name
+	}
+	cb.mu.Lock()
+	cb.subscribe(func(change StateChange) {
+		r.notify(name, change.From, change.To)
+	})
+	cb.mu.Unlock()
+
+	r.breakers[name] = cb
+	return cb
+}
+
+// ForEach calls fn with every breaker created so far, in name order. It works
+// on a snapshot, so fn may use the registry, and breakers created meanwhile
+// are left out.
+func (r *Registry) ForEach(fn func(name string, cb *circuitBreaker)) {
+	r.mu.Lock()
+	breakers := maps.Clone(r.breakers)
+	r.mu.Unlock()
+
+	for _, name := range slices.Sorted(maps.Keys(breakers)) {
+		fn(name, breakers[name])
+	}
+}
+
+// Reset resets the breaker registered under name, reporting whether there was
+// such a breaker
+func (r *Registry) Reset(name string) bool {
+	r.mu.Lock()
+	cb, ok := r.breakers[name]
+	r.mu.Unlock()
+	if !ok {
+		return false
+	}
+
+	cb.Reset()
+	return true
+}
+
+// OnStateChange registers hook to be called with the breaker's name whenever
+// any breaker in the registry changes state. It applies to breakers created
+// both before and after the call. Hooks run synchronously on the goroutine
+// that caused the transition, after the breaker's lock is released.
+func (r *Registry) OnStateChange(hook func(name string, from, to State)) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.hooks = append(r.hooks, hook)
+}
+
+// notify fires the registry's state change hooks outside its lock
+func (r *Registry) notify(name string, from, to State) {
+	r.mu.Lock()
+	hooks := r.hooks
+	r.mu.Unlock()
+
+	for _, hook := range hooks {
+		hook(name, from, to)
+	}
+}
@@ -0,0 +1,107 @@
+package cb
+
+import (
+	"fmt"
+	"slices"
+	"testing"
+	"time"
+)
+
+func TestRegistry_Get(t *testing.T) {
+	t.Parallel()
+
+	r := NewRegistry(func() *circuitBreaker {
+		return NewCircuitBreaker(1, time.Second, 1, 2*time.Second)
+	})
+
+	a := r.Get("a")
+	if a != r.Get("a") {
+		t.Fatalf("expected the same breaker for the same name")
+	}
+
+	if a == r.Get("b") {
+		t.Fatalf("expected distinct breakers for distinct names")
+	}
+
+	if a.name != "a" {
+		t.Fatalf("expected the breaker to take on its registry name, got %q", a.name)
+	}
+}
+
+func TestRegistry_OnStateChange(t *testing.T) {
+	t.Parallel()
+
+	r := NewRegistry(func() *circuitBreaker {
+		return NewCircuitBreaker(1, time.Second, 1, 2*time.Second)
+	})
+
+	// Created before the hook is registered
+	early := r.Get("early")
+
+	var events []string
+	r.OnStateChange(func(name string, from, to State) {
+		events = append(events, fmt.Sprintf("%s:%s->%s", name, from, to))
+
+		// Hooks run outside the breaker's lock, so calling back is safe
+		_ = r.Get(name).Stats()
+	})
+
+	late := r.Get("late")
+
+	failFn := func() (any, error) {
+		return nil, errFailure
+	}
+	_, _ = early.Call(failFn)
+	_, _ = late.Call(failFn)
+
+	want := []string{"early:closed->open", "late:closed->open"}
+	if fmt.Sprint(events) != fmt.Sprint(want) {
+		t.Fatalf("expected events %v, got %v", want, events)
+	}
+}
+
+func TestManager_ForEach(t *testing.T) {
+	t.Parallel()
+
+	m := NewManager(WithFailureThreshold(2), WithRecoveryTime(time.Minute))
+	for _, name := range []string{"users", "orders", "payments"} {
+		m.Get(name)
+	}
+
+	var names []string
+	m.ForEach(func(name string, cb *circuitBreaker) {
+		if cb.failureThreshold != 2 {
+			t.Errorf("expected %s to share the default threshold, got %d", name, cb.failureThreshold)
+		}
+		names = append(names, name)
+	})
+
+	want := []string{"orders", "payments", "users"}
+	if !slices.Equal(names, want) {
+		t.Fatalf("expected %v, got %v", want, names)
+	}
+}
+
+func TestManager_Reset(t *testing.T) {
+	t.Parallel()
+
+	m := NewManager(WithFailureThreshold(1), WithRecoveryTime(time.Minute))
+	cb := m.Get("users")
+	_, _ = cb.Call(func() (any, error) {
+		return nil, errFailure
+	})
+	if cb.State() != Open {
+		t.Fatalf("expected state open, got %s", cb.State())
+	}
+
+	if !m.Reset("users") {
+		t.Fatalf("expected the breaker to be found")
+	}
+	if cb.State() != Closed {
+		t.Fatalf("expected state closed after reset, got %s", cb.State())
+	}
+
+	if m.Reset("unknown") {
+		t.Fatalf("expected resetting an unknown breaker to report false")
+	}
+}
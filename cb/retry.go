@@ -0,0 +1,49 @@
+package cb
+
+// retryBudget caps retries to a fraction of the initial requests so that
+// callers retrying on top of the breaker can't amplify an outage
+type retryBudget struct {
+	ratio    float64 // Maximum ratio of retries to initial requests
+	requests int     // Number of initial requests seen
+	retries  int     // Number of retries admitted
+}
+
+// WithRetryBudget limits calls marked with CallRetry to ratio times the number
+// of initial calls, e.g. 0.1 for one retry per ten requests. Retries beyond
+// the budget are rejected with ErrRetryBudgetExceeded in every state, even
+// when the circuit is closed. The budget is tracked over the breaker's
+// lifetime.
+func WithRetryBudget(ratio float64) Option {
+	return func(cb *circuitBreaker) {
+		cb.retryBudget = &retryBudget{ratio: ratio}
+	}
+}
+
+// CallRetry marks a call as a retry of an earlier one, so that it draws from
+// the retry budget
+func CallRetry() CallOption {
+	return func(c *call) {
+		c.retry = true
+	}
+}
+
+// admitRetry records the call against the retry budget, reporting false if
+// it's a retry the budget can't afford
+func (cb *circuitBreaker) admitRetry(c *call) bool {
+	b := cb.retryBudget
+	if b == nil {
+		return true
+	}
+
+	if !c.retry {
+		b.requests++
+		return true
+	}
+
+	if float64(b.retries+1) > b.ratio*float64(b.requests) {
+		return false
+	}
+
+	b.retries++
+	return true
+}
@@ -0,0 +1,56 @@
+package cb
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestCircuitBreaker_RetryBudget(t *testing.T) {
+	t.Parallel()
+
+	cb := NewCircuitBreaker(100, time.Second, 1, 2*time.Second, WithRetryBudget(0.2))
+
+	calls := 0
+	failFn := func() (any, error) {
+		calls++
+		return nil, errFailure
+	}
+
+	// No initial requests yet, so there's nothing to retry against
+	if _, err := cb.Call(failFn, CallRetry()); !errors.Is(err, ErrRetryBudgetExceeded) {
+		t.Fatalf("expected ErrRetryBudgetExceeded without initial requests, got %v", err)
+	}
+
+	for i := 0; i < 10; i++ {
+		_, _ = cb.Call(failFn)
+	}
+
+	// 10 requests at 20% afford 2 retries
+	for i := 0; i < 2; i++ {
+		if _, err := cb.Call(failFn, CallRetry()); !errors.Is(err, errFailure) {
+			t.Fatalf("expected retry %d to run, got %v", i+1, err)
+		}
+	}
+
+	if _, err := cb.Call(failFn, CallRetry()); !errors.Is(err, ErrRetryBudgetExceeded) {
+		t.Fatalf("expected ErrRetryBudgetExceeded once the budget is spent, got %v", err)
+	}
+
+	if cb.state != Closed {
+		t.Fatalf("expected the budget to apply while closed, got %s", cb.state)
+	}
+
+	if calls != 12 {
+		t.Fatalf("expected 12 calls to run, got %d", calls)
+	}
+
+	// More initial requests refill the budget
+	for i := 0; i < 5; i++ {
+		_, _ = cb.Call(failFn)
+	}
+
+	if _, err := cb.Call(failFn, CallRetry()); !errors.Is(err, errFailure) {
+		t.Fatalf("expected a retry to run after more requests, got %v", err)
+	}
+}
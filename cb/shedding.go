@@ -0,0 +1,60 @@
+package cb
+
+// SheddingController maps the failure ratio observed in closed state, in
+// [0, 1], to the fraction of requests to admit, in [0, 1]
+type SheddingController func(failureRatio float64) float64
+
+// minAdmission is the fraction of requests admitted however degraded the
+// dependency looks. Shed requests aren't counted, so without this trickle the
+// failure ratio could never change again and the breaker would neither trip
+// nor notice the dependency healing.
+const minAdmission = 0.05
+
+// LinearShedding returns a controller that admits all traffic while healthy
+// and sheds proportionally more as the failure ratio grows, admitting only the
+// minimum trickle once it reaches maxRatio. It returns nil, which disables
+// shedding, for a maxRatio of zero or less.
+func LinearShedding(maxRatio float64) SheddingController {
+	if maxRatio <= 0 {
+		return nil
+	}
+	return func(failureRatio float64) float64 {
+		return 1 - failureRatio/maxRatio
+	}
+}
+
+// WithGradualShedding makes the closed circuit admit only a fraction of the
+// requests, decided by controller from the current failure ratio, so traffic
+// ramps down smoothly as the dependency degrades instead of being cut off all
+// at once. Shed requests are rejected with ErrLoadShed. At least 5% of the
+// requests are admitted whatever the controller says, so the breaker keeps
+// seeing outcomes: enough to trip while the dependency is down, and to ramp
+// back up once it heals. A nil controller disables shedding, and Builder
+// rejects it.
+func WithGradualShedding(controller SheddingController) Option {
+	return func(cb *circuitBreaker) {
+		cb.shedding = controller
+		cb.noShedding = controller == nil
+	}
+}
+
+// admissionFraction returns the fraction of requests currently admitted
+func (cb *circuitBreaker) admissionFraction() float64 {
+	if cb.shedding == nil {
+		return 1
+	}
+
+	ratio := 0.0
+	if cb.counts.Requests > 0 {
+		ratio = float64(cb.counts.TotalFailures) / float64(cb.counts.Requests)
+	}
+	return min(max(cb.shedding(ratio), minAdmission), 1)
+}
+
+// shed reports whether the current request should be turned away
+func (cb *circuitBreaker) shed() bool {
+	if cb.shedding == nil {
+		return false
+	}
+	return cb.rand.Float64() >= cb.admissionFraction()
+}
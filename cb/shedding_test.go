@@ -0,0 +1,147 @@
+package cb
+
+import (
+	"errors"
+	"math"
+	"math/rand"
+	"testing"
+	"time"
+)
+
+func TestCircuitBreaker_AdmissionFractionTracksFailureRatio(t *testing.T) {
+	t.Parallel()
+
+	cb := NewCircuitBreaker(100, time.Second, 1, 2*time.Second, WithGradualShedding(LinearShedding(1)))
+
+	if got := cb.Stats().AdmissionFraction; got != 1 {
+		t.Fatalf("expected full admission before any request, got %v", got)
+	}
+
+	// Each outcome is recorded before the next request is considered, so keep
+	// calling until one runs
+	callUntilAdmitted := func(fn func() (any, error)) {
+		for {
+			if _, err := cb.Call(fn); !errors.Is(err, ErrLoadShed) {
+				return
+			}
+		}
+	}
+
+	successFn := func() (any, error) {
+		return 42, nil
+	}
+	failFn := func() (any, error) {
+		return nil, errFailure
+	}
+
+	callUntilAdmitted(successFn)
+	callUntilAdmitted(failFn)
+	callUntilAdmitted(failFn)
+	callUntilAdmitted(failFn)
+
+	// 3 of 4 requests failed
+	if got := cb.Stats().AdmissionFraction; math.Abs(got-0.25) > 1e-9 {
+		t.Fatalf("expected admission fraction 0.25, got %v", got)
+	}
+
+	callUntilAdmitted(successFn)
+	callUntilAdmitted(successFn)
+	callUntilAdmitted(successFn)
+	callUntilAdmitted(successFn)
+
+	// 3 of 8 requests failed
+	if got := cb.Stats().AdmissionFraction; math.Abs(got-0.625) > 1e-9 {
+		t.Fatalf("expected admission fraction 0.625, got %v", got)
+	}
+}
+
+func TestCircuitBreaker_GradualSheddingAdmitsFraction(t *testing.T) {
+	t.Parallel()
+
+	cb := NewCircuitBreaker(
+		100, time.Second, 1, 2*time.Second,
+		WithRandSource(rand.NewSource(1)),
+		WithGradualShedding(func(float64) float64 { return 0.3 }),
+	)
+
+	admitted := 0
+	for i := 0; i < 1000; i++ {
+		_, err := cb.Call(func() (any, error) {
+			return 42, nil
+		})
+		if err == nil {
+			admitted++
+		}
+	}
+
+	if admitted < 250 || admitted > 350 {
+		t.Fatalf("expected roughly 300 of 1000 requests admitted, got %d", admitted)
+	}
+}
+
+func TestCircuitBreaker_GradualSheddingRecovers(t *testing.T) {
+	t.Parallel()
+
+	cb := NewCircuitBreaker(
+		100, time.Second, 1, 2*time.Second,
+		WithRandSource(rand.NewSource(1)),
+		WithGradualShedding(LinearShedding(0.5)),
+	)
+
+	for i := 0; i < 2; i++ {
+		_, _ = cb.Call(func() (any, error) {
+			return nil, errFailure
+		})
+	}
+	if got := cb.Stats().AdmissionFraction; got != minAdmission {
+		t.Fatalf("expected only the minimum admitted past the max ratio, got %v", got)
+	}
+
+	// The dependency heals, and the trickle of admitted requests brings the
+	// failure ratio back down
+	for i := 0; i < 1000 && cb.Stats().AdmissionFraction < 0.5; i++ {
+		_, _ = cb.Call(func() (any, error) {
+			return 42, nil
+		})
+	}
+	if got := cb.Stats().AdmissionFraction; got < 0.5 {
+		t.Fatalf("expected admission to ramp back up, got %v", got)
+	}
+}
+
+func TestCircuitBreaker_GradualSheddingStillTrips(t *testing.T) {
+	t.Parallel()
+
+	cb := NewCircuitBreaker(
+		3, time.Second, 1, 2*time.Second,
+		WithRandSource(rand.NewSource(1)),
+		WithGradualShedding(LinearShedding(0.5)),
+	)
+
+	for i := 0; i < 1000 && cb.State() == Closed; i++ {
+		_, _ = cb.Call(func() (any, error) {
+			return nil, errFailure
+		})
+	}
+	if got := cb.State(); got != Open {
+		t.Fatalf("expected the admitted trickle to trip the circuit, got %v", got)
+	}
+}
+
+func TestLinearShedding_NonPositiveMaxRatio(t *testing.T) {
+	t.Parallel()
+
+	for _, maxRatio := range []float64{0, -1} {
+		if LinearShedding(maxRatio) != nil {
+			t.Fatalf("expected no controller for max ratio %v", maxRatio)
+		}
+	}
+
+	cb := NewCircuitBreaker(100, time.Second, 1, 2*time.Second, WithGradualShedding(LinearShedding(0)))
+	_, _ = cb.Call(func() (any, error) {
+		return nil, errFailure
+	})
+	if got := cb.Stats().AdmissionFraction; got != 1 {
+		t.Fatalf("expected full admission without a controller, got %v", got)
+	}
+}
@@ -0,0 +1,56 @@
+package cb
+
+import "context"
+
+// Shutdown stops the breaker for good, for apps that create breakers
+// dynamically and tests that shouldn't leak goroutines. It stops the health
+// probing goroutine, closes every channel returned by Subscribe, ends the
+// watches of OpenContext, and makes every later call fail with ErrShutdown.
+// It waits for a health check in progress to finish, returning ctx's error if
+// ctx is done first. Shutting down more than once is a no-op.
+//
+// Unlike Close, which forces the circuit closed, Shutdown retires the breaker
+// itself.
+func (cb *circuitBreaker) Shutdown(ctx context.Context) error {
+	cb.mu.Lock()
+	if cb.isShutdown() {
+		cb.mu.Unlock()
+		return nil
+	}
+	close(cb.shutdown)
+
+	p := cb.prober
+	cb.prober = nil
+	subs := cb.subscriptions
+	cb.subscriptions = nil
+	for _, sub := range subs {
+		cb.unsubscribe(sub.id)
+	}
+	cb.log().Info("Circuit breaker shut down")
+	cb.unlock()
+
+	for _, sub := range subs {
+		sub.close()
+	}
+
+	if p == nil {
+		return nil
+	}
+	close(p.stop)
+	select {
+	case <-p.done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// isShutdown reports whether Shutdown was called
+func (cb *circuitBreaker) isShutdown() bool {
+	select {
+	case <-cb.shutdown:
+		return true
+	default:
+		return false
+	}
+}
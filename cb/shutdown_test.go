@@ -0,0 +1,78 @@
+package cb
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestCircuitBreaker_Shutdown(t *testing.T) {
+	t.Parallel()
+
+	cb := NewCircuitBreaker(1, time.Minute, 1, time.Second, WithHealthCheck(func() error {
+		return nil
+	}))
+	cb.StartProbing(time.Millisecond)
+	ch := cb.Subscribe()
+	ctx := cb.OpenContext(context.Background())
+
+	if err := cb.Shutdown(context.Background()); err != nil {
+		t.Fatalf("expected a clean shutdown, got %v", err)
+	}
+
+	if _, ok := <-ch; ok {
+		t.Fatalf("expected the subscription channel to be closed")
+	}
+	select {
+	case <-ctx.Done():
+	case <-time.After(time.Second):
+		t.Fatalf("expected the open context to be cancelled")
+	}
+	if cause := context.Cause(ctx); !errors.Is(cause, ErrShutdown) {
+		t.Fatalf("expected ErrShutdown as the cause, got %v", cause)
+	}
+	if _, ok := <-cb.Subscribe(); ok {
+		t.Fatalf("expected a subscription after shutdown to come back closed")
+	}
+
+	ran := false
+	_, err := cb.Call(func() (any, error) {
+		ran = true
+		return 42, nil
+	})
+	if !errors.Is(err, ErrShutdown) || ran {
+		t.Fatalf("expected ErrShutdown without running the function, got %v", err)
+	}
+	if allowed, _ := cb.Allow(); allowed {
+		t.Fatalf("expected Allow to refuse after shutdown")
+	}
+
+	if err := cb.Shutdown(context.Background()); err != nil {
+		t.Fatalf("expected a second shutdown to be a no-op, got %v", err)
+	}
+	cb.Unsubscribe(ch)
+}
+
+func TestCircuitBreaker_ShutdownWaitsForHealthCheck(t *testing.T) {
+	t.Parallel()
+
+	started, release := make(chan struct{}), make(chan struct{})
+	clock := newFakeClock()
+	cb := NewCircuitBreaker(1, time.Minute, 1, time.Second, WithClock(clock), WithHealthCheck(func() error {
+		close(started)
+		<-release
+		return nil
+	}))
+	cb.Trip()
+	clock.Advance(time.Minute + time.Millisecond)
+	cb.StartProbing(time.Millisecond)
+	<-started
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	if err := cb.Shutdown(ctx); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("expected the shutdown to give up on the hung health check, got %v", err)
+	}
+	close(release)
+}
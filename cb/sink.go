@@ -0,0 +1,77 @@
+package cb
+
+// Names of the series emitted to a MetricsSink
+const (
+	MetricCalls       = "circuit_breaker_calls"            // Counter of calls by state and outcome, timeouts being failures
+	MetricTimeouts    = "circuit_breaker_timeouts"         // Counter of calls that timed out
+	MetricDuration    = "circuit_breaker_duration_seconds" // Duration of calls that ran to completion
+	MetricTransitions = "circuit_breaker_transitions"      // Counter of state transitions by from and to state
+	MetricFailures    = "circuit_breaker_failures"         // Counter of calls that got no result, by reason
+	MetricFallbacks   = "circuit_breaker_fallbacks"        // Counter of calls handed to the fallback, by result
+)
+
+// MetricsSink receives the breaker's metrics at each decision point, so they
+// can be adapted to Prometheus, StatsD, OpenTelemetry, or a test spy. Every
+// series is labeled with the breaker's name under "breaker". Implementations
+// must be safe for concurrent use and must not call back into the breaker,
+// since they're called while its lock is held.
+type MetricsSink interface {
+	Incr(name string, labels map[string]string)
+	Observe(name string, value float64, labels map[string]string)
+}
+
+// noopSink is a MetricsSink that discards everything
+type noopSink struct{}
+
+func (noopSink) Incr(string, map[string]string)             {}
+func (noopSink) Observe(string, float64, map[string]string) {}
+
+// WithMetricsSink sets the sink that receives the breaker's metrics
+func WithMetricsSink(sink MetricsSink) Option {
+	return func(cb *circuitBreaker) {
+		cb.sink = sink
+	}
+}
+
+// emitting reports whether a sink was set, so that the hot path can skip
+// building labels nobody reads
+func (cb *circuitBreaker) emitting() bool {
+	_, noop := cb.sink.(noopSink)
+	return !noop
+}
+
+// emitCall counts a call with the given outcome in the current state
+func (cb *circuitBreaker) emitCall(outcome Outcome) {
+	if !cb.emitting() {
+		return
+	}
+	cb.sink.Incr(MetricCalls, map[string]string{
+		"breaker": cb.name,
+		"state":   cb.state.String(),
+		"outcome": string(outcome),
+	})
+}
+
+// emitFailure counts a call that got no result for reason
+func (cb *circuitBreaker) emitFailure(reason FailureReason) {
+	if !cb.emitting() {
+		return
+	}
+	cb.sink.Incr(MetricFailures, map[string]string{
+		"breaker": cb.name,
+		"reason":  string(reason),
+	})
+}
+
+// emitFallback counts a call handed to the fallback with the given result.
+// Unlike the other series it's emitted after the lock is released, since the
+// fallback runs then, so it goes to the sink taken into c by fallbackFor.
+func (c *call) emitFallback(result FallbackResult) {
+	if _, noop := c.sink.(noopSink); noop {
+		return
+	}
+	c.sink.Incr(MetricFallbacks, map[string]string{
+		"breaker": c.breaker,
+		"result":  string(result),
+	})
+}
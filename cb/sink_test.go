@@ -0,0 +1,74 @@
+package cb
+
+import (
+	"fmt"
+	"slices"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// spySink is a MetricsSink that records every series it receives
+type spySink struct {
+	mu     sync.Mutex
+	series []string
+}
+
+func (s *spySink) record(kind, name string, labels map[string]string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	keys := make([]string, 0, len(labels))
+	for k := range labels {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	pairs := make([]string, 0, len(keys))
+	for _, k := range keys {
+		pairs = append(pairs, fmt.Sprintf("%s=%s", k, labels[k]))
+	}
+	s.series = append(s.series, fmt.Sprintf("%s %s{%s}", kind, name, strings.Join(pairs, ",")))
+}
+
+func (s *spySink) Incr(name string, labels map[string]string) {
+	s.record("incr", name, labels)
+}
+
+func (s *spySink) Observe(name string, _ float64, labels map[string]string) {
+	s.record("observe", name, labels)
+}
+
+func TestCircuitBreaker_MetricsSink(t *testing.T) {
+	t.Parallel()
+
+	sink := &spySink{}
+	cb := NewCircuitBreaker(1, time.Minute, 1, 50*time.Millisecond, WithName("db"), WithMetricsSink(sink))
+
+	_, _ = cb.Call(func() (any, error) {
+		return 42, nil
+	})
+	_, _ = cb.Call(func() (any, error) {
+		time.Sleep(100 * time.Millisecond)
+		return 42, nil
+	})
+	_, _ = cb.Call(func() (any, error) {
+		return 42, nil
+	})
+
+	want := []string{
+		"observe circuit_breaker_duration_seconds{breaker=db,state=closed}",
+		"incr circuit_breaker_calls{breaker=db,outcome=success,state=closed}",
+		"incr circuit_breaker_timeouts{breaker=db}",
+		"incr circuit_breaker_calls{breaker=db,outcome=failure,state=closed}",
+		"incr circuit_breaker_failures{breaker=db,reason=timeout}",
+		"incr circuit_breaker_transitions{breaker=db,from=closed,to=open}",
+		"incr circuit_breaker_calls{breaker=db,outcome=rejected,state=open}",
+		"incr circuit_breaker_failures{breaker=db,reason=rejected}",
+	}
+	if !slices.Equal(sink.series, want) {
+		t.Fatalf("expected series\n%s\ngot\n%s", strings.Join(want, "\n"), strings.Join(sink.series, "\n"))
+	}
+}
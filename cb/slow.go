@@ -0,0 +1,49 @@
+package cb
+
+import "time"
+
+// WithSlowCallRate trips the circuit when at least threshold of the last
+// window successful calls took longer than slow, e.g. 0.5 over 20, since a
+// dependency that answers very slowly is often as bad as one that errors. The
+// circuit never trips on fewer than minimumRequests calls. Failed calls
+// already count toward the failure threshold and aren't sampled. It applies on
+// top of the other trip conditions, and a window or threshold of zero or less
+// turns it off.
+func WithSlowCallRate(slow time.Duration, threshold float64, minimumRequests, window int) Option {
+	return func(cb *circuitBreaker) {
+		cb.slowRate = nil
+		if window > 0 && threshold > 0 {
+			cb.slowCall = slow
+			cb.slowRate = &failureRate{
+				threshold:       threshold,
+				minimumRequests: min(max(minimumRequests, 1), window),
+				outcomes:        make([]bool, window),
+			}
+		}
+	}
+}
+
+// tooSlow reports whether enough of the recent calls were slow to trip the
+// circuit
+func (cb *circuitBreaker) tooSlow() bool {
+	return cb.slowRate != nil && cb.slowRate.exceeded()
+}
+
+// recordSlow samples the latency of a call that succeeded in closed state and
+// trips the circuit if too many recent ones were slow
+func (cb *circuitBreaker) recordSlow(c *call) {
+	if cb.slowRate == nil {
+		return
+	}
+
+	slow := c.duration > cb.slowCall
+	cb.slowRate.record(slow)
+	if slow {
+		cb.totals.slow++
+	}
+
+	if cb.tooSlow() && !cb.warmingUp() && cb.setState(Open) {
+		cb.startRecovery()
+		c.log.Error("Slow call rate reached, transitioning to open", "slowCall", cb.slowCall)
+	}
+}
@@ -0,0 +1,57 @@
+package cb
+
+import (
+	"testing"
+	"time"
+)
+
+func TestCircuitBreaker_SlowCallRate(t *testing.T) {
+	t.Parallel()
+
+	clock := newFakeClock()
+	cb := NewCircuitBreaker(5, time.Minute, 1, 0, WithClock(clock), WithSlowCallRate(time.Second, 0.5, 4, 4))
+	call := func(d time.Duration) {
+		_, _ = cb.Call(func() (any, error) {
+			clock.Advance(d)
+			return 42, nil
+		})
+	}
+
+	call(2 * time.Second)
+	call(10 * time.Millisecond)
+	call(2 * time.Second)
+	if cb.State() != Closed {
+		t.Fatalf("expected no trip below the minimum number of calls, got %s", cb.State())
+	}
+
+	call(10 * time.Millisecond)
+	if cb.State() != Open {
+		t.Fatalf("expected 2 slow calls out of 4 to trip the circuit, got %s", cb.State())
+	}
+	if got := cb.Stats(); got.SlowCalls != 2 || got.Failures != 0 {
+		t.Fatalf("expected 2 slow calls and no failures, got %+v", got)
+	}
+}
+
+func TestCircuitBreaker_SlowCallRateBelowThreshold(t *testing.T) {
+	t.Parallel()
+
+	cb := NewCircuitBreaker(5, time.Minute, 1, time.Second, WithSlowCallRate(50*time.Millisecond, 0.5, 4, 4))
+	for i := 0; i < 8; i++ {
+		d := time.Millisecond
+		if i%4 == 0 {
+			d = 80 * time.Millisecond
+		}
+		_, _ = cb.Call(func() (any, error) {
+			time.Sleep(d)
+			return 42, nil
+		})
+	}
+
+	if cb.State() != Closed {
+		t.Fatalf("expected 1 slow call in 4 to keep the circuit closed, got %s", cb.State())
+	}
+	if got := cb.Stats().SlowCalls; got != 2 {
+		t.Fatalf("expected 2 slow calls, got %d", got)
+	}
+}
@@ -0,0 +1,51 @@
+package cb
+
+import "fmt"
+
+// State is the state of a circuit breaker. The zero value is Closed.
+//
+// Migration note: Closed, Open and HalfOpen used to be untyped string
+// constants. They are now State values; code that compared them with strings
+// should compare against the constants instead, and code that needs the old
+// text should call String, which returns the same "closed", "open" and
+// "half-open" names.
+type State int
+
+const (
+	Closed State = iota
+	Open
+	HalfOpen
+	Disabled // Reported instead of the actual state while the breaker is disabled
+)
+
+// String returns the human-readable name of the state
+func (s State) String() string {
+	switch s {
+	case Closed:
+		return "closed"
+	case Open:
+		return "open"
+	case HalfOpen:
+		return "half-open"
+	case Disabled:
+		return "disabled"
+	default:
+		return "unknown"
+	}
+}
+
+// MarshalText encodes the state as its name, so it reads well in JSON
+func (s State) MarshalText() ([]byte, error) {
+	return []byte(s.String()), nil
+}
+
+// UnmarshalText decodes a state from its name
+func (s *State) UnmarshalText(text []byte) error {
+	for _, state := range []State{Closed, Open, HalfOpen, Disabled} {
+		if state.String() == string(text) {
+			*s = state
+			return nil
+		}
+	}
+	return fmt.Errorf("unknown circuit state %q", text)
+}
@@ -0,0 +1,72 @@
+package cb
+
+import (
+	"testing"
+	"time"
+)
+
+func TestState_String(t *testing.T) {
+	t.Parallel()
+
+	cases := map[State]string{
+		Closed:   "closed",
+		Open:     "open",
+		HalfOpen: "half-open",
+		Disabled: "disabled",
+		State(9): "unknown",
+	}
+	for state, want := range cases {
+		if got := state.String(); got != want {
+			t.Fatalf("expected %q, got %q", want, got)
+		}
+	}
+}
+
+func TestState_ZeroValueIsClosed(t *testing.T) {
+	t.Parallel()
+
+	var s State
+	if s != Closed {
+		t.Fatalf("expected the zero State to be %s, got %s", Closed, s)
+	}
+}
+
+func TestState_UnmarshalTextUnknown(t *testing.T) {
+	t.Parallel()
+
+	var s State
+	if err := s.UnmarshalText([]byte("ajar")); err == nil {
+		t.Fatalf("expected an error for an unknown state")
+	}
+}
+
+func TestCircuitBreaker_UnknownStateSelfHeals(t *testing.T) {
+	t.Parallel()
+
+	var changes []State
+	cb := NewCircuitBreaker(1, time.Minute, 1, time.Second, WithOnStateChange(func(_, to State) {
+		changes = append(changes, to)
+	}))
+	cb.state = State(42)
+
+	result, err := cb.Call(func() (any, error) {
+		return 42, nil
+	})
+	if err != nil || result != 42 {
+		t.Fatalf("expected the call to go through once healed, got %v, %v", result, err)
+	}
+	if cb.State() != Closed {
+		t.Fatalf("expected the breaker to reset to closed, got %s", cb.State())
+	}
+	if len(changes) != 1 || changes[0] != Closed {
+		t.Fatalf("expected a transition to closed, got %v", changes)
+	}
+
+	// The healed breaker works as usual
+	_, _ = cb.Call(func() (any, error) {
+		return nil, errFailure
+	})
+	if cb.State() != Open {
+		t.Fatalf("expected the healed breaker to trip, got %s", cb.State())
+	}
+}
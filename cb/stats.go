@@ -0,0 +1,106 @@
+package cb
+
+import "time"
+
+// maxOperations caps the number of distinct operation names tracked by
+// CallNamed to keep memory bounded
+const maxOperations = 64
+
+// OtherOperation is the bucket for operations beyond the maxOperations limit
+const OtherOperation = "other"
+
+// Stats is a point-in-time snapshot of the circuit breaker, taken in a single
+// locked read
+type Stats struct {
+	State             State              // Current state of the circuit breaker
+	Counts            Counts             // Counters of the current state, e.g. failures toward the trip or half-open successes
+	LastFailureTime   time.Time          // When the last failure was recorded or the circuit last opened
+	LastFailureReason FailureReason      // Reason of the last failure, ReasonError or ReasonTimeout
+	OpenUntil         time.Time          // When the open circuit is due to recover, zero unless open
+	Requests          int                // Cumulative number of calls made through the breaker
+	Successes         int                // Cumulative number of calls that succeeded
+	Failures          int                // Cumulative number of calls that failed, timeouts included
+	Rejections        int                // Cumulative number of calls rejected without running
+	Timeouts          int                // Cumulative number of calls that timed out
+	Throttled         int                // Cumulative number of calls rejected by the rate limit, included in Rejections
+	Canceled          int                // Cumulative number of calls the caller gave up on
+	SlowCalls         int                // Cumulative number of successful calls slower than the slow call threshold
+	InFlight          int                // Number of admitted calls yet to finish
+	ByOperation       map[string]OpStats // Cumulative counters per named operation
+	AdmissionFraction float64            // Fraction of requests admitted in closed state
+}
+
+// OpStats holds the cumulative counters of a single named operation
+type OpStats struct {
+	Successes  int // Number of calls that succeeded
+	Failures   int // Number of calls that failed
+	Rejections int // Number of calls rejected without running
+}
+
+// Stats returns a snapshot of the circuit breaker's state and counters
+func (cb *circuitBreaker) Stats() Stats {
+	cb.mu.Lock()
+	defer cb.mu.Unlock()
+
+	cb.foldRejections()
+	byOperation := make(map[string]OpStats, len(cb.operations))
+	for op, s := range cb.operations {
+		byOperation[op] = *s
+	}
+
+	return Stats{
+		State:             cb.reportedState(),
+		Counts:            cb.counts,
+		LastFailureTime:   cb.lastFailureTime,
+		LastFailureReason: cb.lastFailureReason,
+		OpenUntil:         cb.openDeadline(),
+		Requests:          cb.totals.requests,
+		Successes:         cb.totals.successes,
+		Failures:          cb.totals.failures,
+		Rejections:        cb.totals.rejections,
+		Timeouts:          cb.totals.timeouts,
+		Throttled:         cb.totals.throttled,
+		Canceled:          cb.totals.canceled,
+		SlowCalls:         cb.totals.slow,
+		InFlight:          cb.inFlight,
+		ByOperation:       byOperation,
+		AdmissionFraction: cb.admissionFraction(),
+	}
+}
+
+// opStats returns the counters for op, creating them on first use. Unnamed
+// calls get a throwaway value so callers needn't check.
+func (cb *circuitBreaker) opStats(op string) *OpStats {
+	if op == "" {
+		return &OpStats{}
+	}
+
+	if s, ok := cb.operations[op]; ok {
+		return s
+	}
+
+	if cb.operations == nil {
+		cb.operations = make(map[string]*OpStats)
+	}
+
+	// The overflow bucket doesn't count toward the limit
+	if len(cb.operations) >= maxOperations {
+		op = OtherOperation
+		if s, ok := cb.operations[op]; ok {
+			return s
+		}
+	}
+
+	s := &OpStats{}
+	cb.operations[op] = s
+	return s
+}
+
+// openDeadline returns when the open circuit is due to recover, or the zero
+// time if it isn't open
+func (cb *circuitBreaker) openDeadline() time.Time {
+	if cb.state != Open || cb.disabled {
+		return time.Time{}
+	}
+	return cb.openUntil
+}
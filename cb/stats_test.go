@@ -0,0 +1,163 @@
+package cb
+
+import (
+	"fmt"
+	"testing"
+	"time"
+)
+
+func TestCircuitBreaker_CallNamedStats(t *testing.T) {
+	t.Parallel()
+
+	cb := NewCircuitBreaker(3, 5*time.Second, 1, 2*time.Second)
+
+	successFn := func() (any, error) {
+		return 42, nil
+	}
+	failFn := func() (any, error) {
+		return nil, errFailure
+	}
+
+	_, _ = cb.CallNamed("read", successFn)
+	_, _ = cb.CallNamed("read", successFn)
+	_, _ = cb.CallNamed("write", failFn)
+	_, _ = cb.CallNamed("write", failFn)
+
+	// Failures of both operations feed the same trip decision
+	_, _ = cb.CallNamed("read", failFn)
+	if cb.state != Open {
+		t.Fatalf("expected shared failures to trip the circuit, got %s", cb.state)
+	}
+
+	_, _ = cb.CallNamed("read", successFn)
+
+	stats := cb.Stats()
+	if got, want := stats.ByOperation["read"], (OpStats{Successes: 2, Failures: 1, Rejections: 1}); got != want {
+		t.Fatalf("expected read stats %+v, got %+v", want, got)
+	}
+
+	if got, want := stats.ByOperation["write"], (OpStats{Failures: 2}); got != want {
+		t.Fatalf("expected write stats %+v, got %+v", want, got)
+	}
+
+	// Unnamed calls aren't bucketed
+	_, _ = cb.Call(successFn)
+	if len(cb.Stats().ByOperation) != 2 {
+		t.Fatalf("expected 2 operations, got %v", cb.Stats().ByOperation)
+	}
+}
+
+func TestCircuitBreaker_CallNamedBoundedOperations(t *testing.T) {
+	t.Parallel()
+
+	cb := NewCircuitBreaker(3, 5*time.Second, 1, 2*time.Second)
+
+	successFn := func() (any, error) {
+		return 42, nil
+	}
+
+	for i := 0; i < maxOperations+10; i++ {
+		_, _ = cb.CallNamed(fmt.Sprintf("op-%d", i), successFn)
+	}
+
+	stats := cb.Stats()
+	if len(stats.ByOperation) != maxOperations+1 {
+		t.Fatalf("expected %d operations, got %d", maxOperations+1, len(stats.ByOperation))
+	}
+
+	if got := stats.ByOperation[OtherOperation].Successes; got != 10 {
+		t.Fatalf("expected 10 successes in the overflow bucket, got %d", got)
+	}
+}
+
+func TestCircuitBreaker_StatsSnapshot(t *testing.T) {
+	t.Parallel()
+
+	clock := newFakeClock()
+	cb := NewCircuitBreaker(2, time.Minute, 1, 10*time.Millisecond, WithClock(clock))
+
+	_, _ = cb.Call(func() (any, error) {
+		return 42, nil
+	})
+	_, _ = cb.Call(func() (any, error) {
+		time.Sleep(time.Second)
+		return 42, nil
+	})
+
+	stats := cb.Stats()
+	if stats.State != Closed || stats.Counts.ConsecutiveFailures != 1 {
+		t.Fatalf("expected a closed circuit one failure short of tripping, got %+v", stats)
+	}
+	if !stats.LastFailureTime.Equal(clock.Now()) {
+		t.Fatalf("expected the last failure time %v, got %v", clock.Now(), stats.LastFailureTime)
+	}
+
+	_, _ = cb.Call(func() (any, error) {
+		return nil, errFailure
+	})
+	_, _ = cb.Call(func() (any, error) {
+		return 42, nil
+	})
+
+	stats = cb.Stats()
+	want := Stats{State: Open, Requests: 4, Successes: 1, Failures: 2, Rejections: 1, Timeouts: 1}
+	if stats.State != want.State || stats.Requests != want.Requests || stats.Successes != want.Successes ||
+		stats.Failures != want.Failures || stats.Rejections != want.Rejections || stats.Timeouts != want.Timeouts {
+		t.Fatalf("expected totals %+v, got %+v", want, stats)
+	}
+}
+
+func TestCircuitBreaker_LastFailureReason(t *testing.T) {
+	t.Parallel()
+
+	cb := NewCircuitBreaker(5, time.Minute, 1, 20*time.Millisecond)
+	if got := cb.Stats().LastFailureReason; got != "" {
+		t.Fatalf("expected no failure reason before any failure, got %q", got)
+	}
+
+	_, _ = cb.Call(func() (any, error) {
+		time.Sleep(50 * time.Millisecond)
+		return 42, nil
+	})
+	if got := cb.Stats().LastFailureReason; got != ReasonTimeout {
+		t.Fatalf("expected %s, got %s", ReasonTimeout, got)
+	}
+
+	_, _ = cb.Call(func() (any, error) {
+		return nil, errFailure
+	})
+	if got := cb.Stats().LastFailureReason; got != ReasonError {
+		t.Fatalf("expected %s, got %s", ReasonError, got)
+	}
+
+	// A rejection isn't a failure of the dependency, so it leaves the reason be
+	cb.Trip()
+	_, _ = cb.Call(func() (any, error) {
+		return 42, nil
+	})
+	if got := cb.Stats().LastFailureReason; got != ReasonError {
+		t.Fatalf("expected the rejection to keep %s, got %s", ReasonError, got)
+	}
+}
+
+func TestCircuitBreaker_StatsOpenUntil(t *testing.T) {
+	t.Parallel()
+
+	clock := newFakeClock()
+	cb := NewCircuitBreaker(1, time.Minute, 1, time.Second, WithClock(clock))
+	if got := cb.Stats().OpenUntil; !got.IsZero() {
+		t.Fatalf("expected no deadline while closed, got %v", got)
+	}
+
+	_, _ = cb.Call(func() (any, error) {
+		return nil, errFailure
+	})
+	if got, want := cb.Stats().OpenUntil, clock.Now().Add(time.Minute); !got.Equal(want) {
+		t.Fatalf("expected the circuit open until %v, got %v", want, got)
+	}
+
+	cb.Reset()
+	if got := cb.Stats().OpenUntil; !got.IsZero() {
+		t.Fatalf("expected no deadline after a reset, got %v", got)
+	}
+}
@@ -0,0 +1,137 @@
+package cb
+
+import (
+	"errors"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sync"
+)
+
+// Store persists the breaker's state so that it survives restarts. Save is
+// called on every transition with the breaker's lock held, so it must not
+// call back into the breaker, and it should be quick.
+type Store interface {
+	Load() (State, error)
+	Save(State) error
+}
+
+// WithStore makes the breaker start in the state loaded from store and save
+// its state there on every transition. A breaker that loads Open waits out a
+// full recovery period from when it was created, since the store doesn't know
+// when the circuit opened. Errors loading or saving are logged, and a breaker
+// that fails to load starts closed.
+func WithStore(store Store) Option {
+	return func(cb *circuitBreaker) {
+		cb.store = store
+	}
+}
+
+// restore loads the breaker's state from its store, if it has one
+func (cb *circuitBreaker) restore() {
+	if cb.store == nil {
+		return
+	}
+
+	state, err := cb.store.Load()
+	if err != nil {
+		cb.log().Error("Failed to load the circuit state, starting closed", "error", err)
+		return
+	}
+
+	switch state {
+	case Open, HalfOpen:
+		cb.state = state
+		cb.startRecovery()
+		cb.awaitingFirstProbe = state == HalfOpen
+		cb.halfOpenSince = cb.clock.Now()
+	}
+}
+
+// persist saves the breaker's state to its store, if it has one
+func (cb *circuitBreaker) persist() {
+	if cb.store == nil {
+		return
+	}
+
+	if err := cb.store.Save(cb.state); err != nil {
+		cb.log().Error("Failed to save the circuit state", "state", cb.state, "error", err)
+	}
+}
+
+// MemoryStore is a Store that keeps the state in memory, for sharing it
+// between breakers in the same process and for tests. The zero value holds
+// Closed and is ready to use.
+type MemoryStore struct {
+	mu    sync.Mutex
+	state State
+}
+
+// Load returns the last saved state
+func (s *MemoryStore) Load() (State, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.state, nil
+}
+
+// Save records state
+func (s *MemoryStore) Save(state State) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.state = state
+	return nil
+}
+
+// FileStore is a Store that keeps the state in a file, as its name
+type FileStore struct {
+	path string
+}
+
+// NewFileStore returns a FileStore keeping the state in the file at path. The
+// file is created on the first save, and a missing file loads as Closed.
+func NewFileStore(path string) *FileStore {
+	return &FileStore{path: path}
+}
+
+// Load reads the state from the file
+func (s *FileStore) Load() (State, error) {
+	data, err := os.ReadFile(s.path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return Closed, nil
+	}
+	if err != nil {
+		return Closed, err
+	}
+
+	var state State
+	if err := state.UnmarshalText(data); err != nil {
+		return Closed, err
+	}
+	return state, nil
+}
+
+// Save writes state to the file, replacing it in one step so that a crash
+// mid-write can't leave it corrupt
+func (s *FileStore) Save(state State) error {
+	data, err := state.MarshalText()
+	if err != nil {
+		return err
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(s.path), filepath.Base(s.path)+".*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), s.path)
+}
@@ -0,0 +1,85 @@
+package cb
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestFileStore_SurvivesRestart(t *testing.T) {
+	t.Parallel()
+
+	path := filepath.Join(t.TempDir(), "breaker.state")
+	clock := newFakeClock()
+
+	first := NewCircuitBreaker(1, time.Minute, 1, time.Second, WithClock(clock), WithStore(NewFileStore(path)))
+	_, _ = first.Call(func() (any, error) {
+		return nil, errFailure
+	})
+
+	data, err := os.ReadFile(path)
+	if err != nil || string(data) != "open" {
+		t.Fatalf("expected the file to hold open, got %q, %v", data, err)
+	}
+
+	// The restarted breaker waits out a full recovery period from its start
+	clock.Advance(30 * time.Second)
+	restarted := NewCircuitBreaker(1, time.Minute, 1, time.Second, WithClock(clock), WithStore(NewFileStore(path)))
+	if got := restarted.State(); got != Open {
+		t.Fatalf("expected the restarted breaker to be open, got %s", got)
+	}
+	clock.Advance(59 * time.Second)
+	if _, err := restarted.Call(func() (any, error) { return 42, nil }); !errors.Is(err, ErrCircuitOpen) {
+		t.Fatalf("expected ErrCircuitOpen within the recovery period, got %v", err)
+	}
+
+	clock.Advance(2 * time.Second)
+	if _, err := restarted.Call(func() (any, error) { return 42, nil }); err != nil {
+		t.Fatalf("expected the probe to succeed, got %v", err)
+	}
+	if data, _ := os.ReadFile(path); string(data) != "closed" {
+		t.Fatalf("expected the file to hold closed, got %q", data)
+	}
+}
+
+func TestFileStore_MissingAndCorruptFiles(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+	if state, err := NewFileStore(filepath.Join(dir, "missing")).Load(); state != Closed || err != nil {
+		t.Fatalf("expected a missing file to load as closed, got %s, %v", state, err)
+	}
+
+	corrupt := filepath.Join(dir, "corrupt")
+	if err := os.WriteFile(corrupt, []byte("sideways"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := NewFileStore(corrupt).Load(); err == nil {
+		t.Fatalf("expected an error for a corrupt file")
+	}
+
+	cb := NewCircuitBreaker(1, time.Minute, 1, time.Second, WithStore(NewFileStore(corrupt)))
+	if got := cb.State(); got != Closed {
+		t.Fatalf("expected a breaker that fails to load to start closed, got %s", got)
+	}
+}
+
+func TestMemoryStore_SharedAcrossBreakers(t *testing.T) {
+	t.Parallel()
+
+	store := &MemoryStore{}
+	first := NewCircuitBreaker(1, time.Minute, 1, time.Second, WithStore(store))
+	first.Trip()
+
+	second := NewCircuitBreaker(1, time.Minute, 1, time.Second, WithStore(store))
+	if got := second.State(); got != Open {
+		t.Fatalf("expected the second breaker to start open, got %s", got)
+	}
+
+	second.Reset()
+	if state, _ := store.Load(); state != Closed {
+		t.Fatalf("expected the reset to be saved, got %s", state)
+	}
+}
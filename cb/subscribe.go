@@ -0,0 +1,151 @@
+package cb
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// subscriptionBuffer is the number of state changes a subscriber can fall
+// behind by before further ones are dropped
+const subscriptionBuffer = 16
+
+// subscribe registers listener to be notified of every state transition after
+// the lock is released, returning the subscription's ID. The lock must be
+// held.
+func (cb *circuitBreaker) subscribe(listener func(change StateChange)) int {
+	if cb.listeners == nil {
+		cb.listeners = make(map[int]func(change StateChange))
+	}
+
+	id := cb.nextListener
+	cb.nextListener++
+	cb.listeners[id] = listener
+	return id
+}
+
+// unsubscribe removes the subscription with the given ID. The lock must be
+// held.
+func (cb *circuitBreaker) unsubscribe(id int) {
+	delete(cb.listeners, id)
+}
+
+// OpenContext returns a context derived from parent that's cancelled as soon
+// as the breaker opens, with ErrCircuitOpen as its cause. It lets a long
+// running operation bail out once sibling calls have declared the dependency
+// unhealthy. The context is already cancelled if the circuit is open. The
+// watch on the breaker ends when either the circuit opens or parent is done,
+// or with ErrShutdown as the cause when the breaker is shut down.
+func (cb *circuitBreaker) OpenContext(parent context.Context) context.Context {
+	ctx, cancel := context.WithCancelCause(parent)
+
+	cb.mu.Lock()
+	if cb.state == Open {
+		cb.mu.Unlock()
+		cancel(ErrCircuitOpen)
+		return ctx
+	}
+
+	opened := make(chan struct{})
+	var once sync.Once
+	id := cb.subscribe(func(change StateChange) {
+		if change.To == Open {
+			once.Do(func() { close(opened) })
+		}
+	})
+	cb.mu.Unlock()
+
+	go func() {
+		select {
+		case <-opened:
+			cancel(ErrCircuitOpen)
+		case <-cb.shutdown:
+			cancel(ErrShutdown)
+		case <-ctx.Done():
+		}
+
+		cb.mu.Lock()
+		cb.unsubscribe(id)
+		cb.mu.Unlock()
+	}()
+
+	return ctx
+}
+
+// StateChange is a state transition delivered to subscribers
+type StateChange struct {
+	From State     `json:"from"` // State the breaker left
+	To   State     `json:"to"`   // State the breaker entered
+	At   time.Time `json:"at"`   // When the transition happened
+}
+
+// subscription is a channel subscribed to the breaker's transitions
+type subscription struct {
+	mu     sync.Mutex       // Guards closed against sends in flight
+	ch     chan StateChange // Channel handed to the subscriber
+	closed bool             // Whether the subscriber has unsubscribed
+	id     int              // ID of the listener that feeds the channel
+}
+
+// send delivers change unless the subscriber is too far behind or gone
+func (s *subscription) send(change StateChange) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.closed {
+		return
+	}
+	select {
+	case s.ch <- change:
+	default:
+	}
+}
+
+// Subscribe returns a channel that receives every state transition from now
+// on, for consumers that would rather not be called back. The channel is
+// buffered, and transitions that find the buffer full are dropped rather than
+// stalling the breaker, so a subscriber that falls behind misses some. Pass
+// the channel to Unsubscribe once done with it. After Shutdown the channel
+// comes back closed.
+func (cb *circuitBreaker) Subscribe() <-chan StateChange {
+	sub := &subscription{ch: make(chan StateChange, subscriptionBuffer)}
+
+	cb.mu.Lock()
+	defer cb.mu.Unlock()
+
+	if cb.isShutdown() {
+		close(sub.ch)
+		return sub.ch
+	}
+	sub.id = cb.subscribe(sub.send)
+	if cb.subscriptions == nil {
+		cb.subscriptions = make(map[<-chan StateChange]*subscription)
+	}
+	cb.subscriptions[sub.ch] = sub
+	return sub.ch
+}
+
+// Unsubscribe stops deliveries to a channel returned by Subscribe and closes
+// it. It's a no-op for a channel that isn't subscribed.
+func (cb *circuitBreaker) Unsubscribe(ch <-chan StateChange) {
+	cb.mu.Lock()
+	sub, ok := cb.subscriptions[ch]
+	if ok {
+		delete(cb.subscriptions, ch)
+		cb.unsubscribe(sub.id)
+	}
+	cb.mu.Unlock()
+
+	if ok {
+		sub.close()
+	}
+}
+
+// close closes the channel, stopping further deliveries
+func (s *subscription) close() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.closed = true
+	close(s.ch)
+}
@@ -0,0 +1,167 @@
+package cb
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestCircuitBreaker_OpenContextCancelledOnTrip(t *testing.T) {
+	t.Parallel()
+
+	cb := NewCircuitBreaker(1, time.Minute, 1, 2*time.Second)
+	ctx := cb.OpenContext(context.Background())
+
+	select {
+	case <-ctx.Done():
+		t.Fatalf("expected the context to be live while closed")
+	default:
+	}
+
+	// A sibling call trips the breaker
+	_, _ = cb.Call(func() (any, error) {
+		return nil, errFailure
+	})
+
+	select {
+	case <-ctx.Done():
+	case <-time.After(time.Second):
+		t.Fatalf("expected the context to be cancelled once the breaker opens")
+	}
+
+	if cause := context.Cause(ctx); !errors.Is(cause, ErrCircuitOpen) {
+		t.Fatalf("expected ErrCircuitOpen as the cause, got %v", cause)
+	}
+
+	// Already open breakers hand out cancelled contexts
+	if err := cb.OpenContext(context.Background()).Err(); err == nil {
+		t.Fatalf("expected a cancelled context from an open breaker")
+	}
+
+	waitForListeners(t, cb, 0)
+}
+
+func TestCircuitBreaker_OpenContextCleanedUpWithParent(t *testing.T) {
+	t.Parallel()
+
+	cb := NewCircuitBreaker(1, time.Minute, 1, 2*time.Second)
+
+	parent, cancel := context.WithCancel(context.Background())
+	ctx := cb.OpenContext(parent)
+	waitForListeners(t, cb, 1)
+
+	cancel()
+
+	if cause := context.Cause(ctx); !errors.Is(cause, context.Canceled) {
+		t.Fatalf("expected the parent's cancellation as the cause, got %v", cause)
+	}
+
+	// The watcher goroutine unsubscribes once the parent is done
+	waitForListeners(t, cb, 0)
+}
+
+// waitForListeners waits until the breaker has exactly n subscriptions
+func waitForListeners(t *testing.T, cb *circuitBreaker, n int) {
+	t.Helper()
+
+	deadline := time.Now().Add(time.Second)
+	for {
+		cb.mu.Lock()
+		got := len(cb.listeners)
+		cb.mu.Unlock()
+
+		if got == n {
+			return
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("expected %d listeners, got %d", n, got)
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+func TestCircuitBreaker_SubscribeFansOut(t *testing.T) {
+	t.Parallel()
+
+	clock := newFakeClock()
+	cb := NewCircuitBreaker(1, time.Minute, 1, 2*time.Second, WithClock(clock))
+	first, second := cb.Subscribe(), cb.Subscribe()
+
+	_, _ = cb.Call(func() (any, error) {
+		return nil, errFailure
+	})
+
+	for _, ch := range []<-chan StateChange{first, second} {
+		select {
+		case change := <-ch:
+			if change.From != Closed || change.To != Open || !change.At.Equal(clock.Now()) {
+				t.Fatalf("expected closed -> open at %v, got %+v", clock.Now(), change)
+			}
+		default:
+			t.Fatalf("expected every subscriber to receive the transition")
+		}
+	}
+
+	cb.Unsubscribe(first)
+	if _, ok := <-first; ok {
+		t.Fatalf("expected the unsubscribed channel to be closed")
+	}
+	cb.Unsubscribe(first) // A second unsubscribe is a no-op
+
+	cb.Reset()
+	if change := <-second; change.To != Closed {
+		t.Fatalf("expected the remaining subscriber to receive open -> closed, got %+v", change)
+	}
+}
+
+func TestCircuitBreaker_SlowSubscriberDoesntStall(t *testing.T) {
+	t.Parallel()
+
+	cb := NewCircuitBreaker(1, time.Minute, 1, 2*time.Second)
+	ch := cb.Subscribe()
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for i := 0; i < 2*subscriptionBuffer; i++ {
+			cb.Trip()
+			cb.Reset()
+		}
+	}()
+
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatalf("expected transitions to go on without a reader")
+	}
+	if got := len(ch); got != subscriptionBuffer {
+		t.Fatalf("expected a full buffer of %d changes, got %d", subscriptionBuffer, got)
+	}
+	cb.Unsubscribe(ch)
+}
+
+func TestCircuitBreaker_SubscribeStampsTransition(t *testing.T) {
+	t.Parallel()
+
+	clock := newFakeClock()
+	tripped := clock.Now()
+	// The hook runs after the unlock and before subscribers are fed
+	cb := NewCircuitBreaker(1, time.Minute, 1, 2*time.Second, WithClock(clock), WithOnStateChange(func(_, _ State) {
+		clock.Advance(time.Second)
+	}))
+	ch := cb.Subscribe()
+
+	_, _ = cb.Call(func() (any, error) {
+		return nil, errFailure
+	})
+
+	select {
+	case change := <-ch:
+		if !change.At.Equal(tripped) {
+			t.Fatalf("expected the change stamped when the circuit opened at %v, got %v", tripped, change.At)
+		}
+	default:
+		t.Fatalf("expected the subscriber to receive the transition")
+	}
+}
@@ -0,0 +1,60 @@
+package cb
+
+import "time"
+
+// tightening shrinks the timeout of a consistently fast dependency so that a
+// rare regression fails fast instead of running into the full timeout
+type tightening struct {
+	streak    int           // Number of consecutive fast successes needed
+	factor    float64       // Headroom multiplied onto the observed latency
+	successes int           // Current run of consecutive fast successes
+	timeout   time.Duration // Tightened timeout, zero when not tightened
+}
+
+// WithAutoTightening makes the breaker tighten its timeout once streak
+// consecutive calls succeed fast, to the p99 latency times factor, capped at
+// the configured timeout. A call is fast when it takes no more than the
+// current timeout divided by factor. Any failure, timeout, or slow success
+// reverts to the configured timeout and starts the streak over.
+func WithAutoTightening(streak int, factor float64) Option {
+	return func(cb *circuitBreaker) {
+		cb.tightening = &tightening{streak: streak, factor: factor}
+	}
+}
+
+// effectiveTimeout returns the timeout to apply to the next call
+func (cb *circuitBreaker) effectiveTimeout() time.Duration {
+	if t := cb.tightening; t != nil && t.timeout > 0 {
+		return t.timeout
+	}
+	return cb.timeout
+}
+
+// observeTightening feeds a call's latency and outcome to auto-tightening
+func (cb *circuitBreaker) observeTightening(latency time.Duration, ok bool) {
+	t := cb.tightening
+	if t == nil {
+		return
+	}
+
+	fast := float64(latency)*t.factor <= float64(cb.effectiveTimeout())
+	if !ok || !fast {
+		if t.timeout > 0 {
+			cb.log().Info("Reverting tightened timeout", "timeout", cb.timeout)
+		}
+		t.successes = 0
+		t.timeout = 0
+		return
+	}
+
+	t.successes++
+	if t.successes < t.streak {
+		return
+	}
+
+	tightened := time.Duration(float64(cb.latencies.percentile(0.99)) * t.factor)
+	if tightened > 0 && tightened < cb.timeout && tightened != t.timeout {
+		t.timeout = tightened
+		cb.log().Info("Tightening timeout after a fast success streak", "timeout", tightened)
+	}
+}
@@ -0,0 +1,71 @@
+package cb
+
+import (
+	"testing"
+	"time"
+)
+
+func TestCircuitBreaker_AutoTightening(t *testing.T) {
+	t.Parallel()
+
+	clock := newFakeClock()
+	cb := NewCircuitBreaker(3, time.Second, 1, time.Second, WithClock(clock), WithAutoTightening(5, 3))
+
+	fastFn := func() (any, error) {
+		clock.Advance(10 * time.Millisecond)
+		return 42, nil
+	}
+
+	for i := 0; i < 4; i++ {
+		_, _ = cb.Call(fastFn)
+	}
+
+	if got := cb.effectiveTimeout(); got != time.Second {
+		t.Fatalf("expected the configured timeout before the streak completes, got %v", got)
+	}
+
+	// The fifth fast success completes the streak
+	_, _ = cb.Call(fastFn)
+
+	if got := cb.effectiveTimeout(); got != 30*time.Millisecond {
+		t.Fatalf("expected the timeout to tighten to 30ms, got %v", got)
+	}
+
+	// A failure reverts to the configured timeout
+	_, _ = cb.Call(func() (any, error) {
+		clock.Advance(10 * time.Millisecond)
+		return nil, errFailure
+	})
+
+	if got := cb.effectiveTimeout(); got != time.Second {
+		t.Fatalf("expected the timeout to revert after a failure, got %v", got)
+	}
+}
+
+func TestCircuitBreaker_AutoTighteningSlowSuccessReverts(t *testing.T) {
+	t.Parallel()
+
+	clock := newFakeClock()
+	cb := NewCircuitBreaker(3, time.Second, 1, time.Second, WithClock(clock), WithAutoTightening(2, 3))
+
+	callTaking := func(d time.Duration) {
+		_, _ = cb.Call(func() (any, error) {
+			clock.Advance(d)
+			return 42, nil
+		})
+	}
+
+	callTaking(10 * time.Millisecond)
+	callTaking(10 * time.Millisecond)
+
+	if got := cb.effectiveTimeout(); got != 30*time.Millisecond {
+		t.Fatalf("expected the timeout to tighten to 30ms, got %v", got)
+	}
+
+	// 20ms succeeds within the tightened timeout but eats into the headroom
+	callTaking(20 * time.Millisecond)
+
+	if got := cb.effectiveTimeout(); got != time.Second {
+		t.Fatalf("expected the timeout to revert after a slow success, got %v", got)
+	}
+}
@@ -0,0 +1,54 @@
+package cb
+
+import "context"
+
+// CallTracer starts a span for each call through the breaker, so that its
+// decisions show up in traces. It's the extension point of tracing packages
+// such as cbotel, which keep their dependencies out of this one.
+type CallTracer interface {
+	// StartCall starts the span of a call through the named breaker, returning
+	// the context the protected function runs with
+	StartCall(ctx context.Context, breaker string) (context.Context, CallSpan)
+}
+
+// CallSpan is the span of a single call, ended once the breaker has settled
+// it. Its methods are called on the calling goroutine with the breaker's lock
+// released.
+type CallSpan interface {
+	Rejected(err error)                          // The breaker turned the call away with err
+	Tripped()                                    // The call opened the circuit
+	End(state State, outcome Outcome, err error) // The call ended with outcome in state
+}
+
+// WithTracer starts a span with tracer around every call made with Call or
+// one of its variants. Calls made through Allow aren't traced.
+func WithTracer(tracer CallTracer) Option {
+	return func(cb *circuitBreaker) {
+		cb.tracer = tracer
+	}
+}
+
+// startSpan starts the span of a call with tracer, if there is one, once the
+// breaker's lock is released. The function runs with the span's context.
+func startSpan(c *call, tracer CallTracer, breaker string) CallSpan {
+	if tracer == nil {
+		return nil
+	}
+	var span CallSpan
+	c.ctx, span = tracer.StartCall(c.ctx, breaker)
+	return span
+}
+
+// endSpan reports how the call went to its span, if it has one
+func endSpan(span CallSpan, c *call, tripped bool, err error) {
+	if span == nil {
+		return
+	}
+	if c.outcome == OutcomeRejected {
+		span.Rejected(err)
+	}
+	if tripped {
+		span.Tripped()
+	}
+	span.End(c.madeIn, c.outcome, err)
+}
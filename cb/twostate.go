@@ -0,0 +1,59 @@
+package cb
+
+// WithoutHalfOpen runs the breaker with only two states. Once the recovery
+// time has passed, the next call is let through as a retry while the circuit
+// is still open: if it succeeds, the circuit closes for all traffic at once,
+// and if it fails, the circuit stays open for another recovery period. Other
+// callers are rejected while the retry runs.
+//
+// Compared to the half-open ramp, recovery takes a single request rather
+// than a series of probes, so it's quicker and simpler to reason about. The
+// price is that one lucky success lets full traffic back onto a dependency
+// that may only be partly recovered, where half-open would have kept probing
+// first.
+func WithoutHalfOpen() Option {
+	return func(cb *circuitBreaker) {
+		cb.twoState = true
+	}
+}
+
+// handleRetry admits the call as the retry of a two-state circuit whose
+// recovery period is over, unless another retry is already running
+func (cb *circuitBreaker) handleRetry(c *call) (any, error) {
+	if cb.probing {
+		c.log.Debug("Retry in flight, blocking request")
+		cb.rejectOpen(c)
+		// A failing retry keeps the circuit open for another recovery period
+		return nil, &OpenError{retryAfter: cb.recoveryWindow()}
+	}
+
+	c.log.Info("Recovery period over, retrying")
+	cb.probing = true
+	cb.admit(c)
+	return nil, nil
+}
+
+// finishRetry closes the two-state circuit if its retry succeeded, or keeps it
+// open for another recovery period if it failed
+func (cb *circuitBreaker) finishRetry(c *call, result any, err error) (any, error) {
+	cb.probing = false
+
+	if cls := cb.classify(c, err); cls.IsFailure {
+		c.log.Error("Retry failed, staying open", "retryable", cls.Retryable, "reason", c.failureReason())
+		cb.tallyFailure(c)
+		if cls.Retryable {
+			cb.openFor = cb.retryableRecovery
+		}
+		cb.reopens++ // Backs off just as if a half-open round had failed
+		cb.startRecovery()
+		return nil, err
+	}
+
+	cb.tallySuccess(c)
+	c.log.Info("Retry succeeded, transitioning to closed")
+	if !cb.resetCircuit() {
+		// A vetoed close waits out another full recovery period
+		cb.startRecovery()
+	}
+	return result, err
+}
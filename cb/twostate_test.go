@@ -0,0 +1,157 @@
+package cb
+
+import (
+	"errors"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestCircuitBreaker_WithoutHalfOpenRetrySucceeds(t *testing.T) {
+	t.Parallel()
+
+	clock := newFakeClock()
+	var seen []State
+	cb := NewCircuitBreaker(1, time.Minute, 3, time.Second, WithClock(clock), WithoutHalfOpen(), WithOnStateChange(func(from, to State) {
+		seen = append(seen, to)
+	}))
+
+	_, _ = cb.Call(func() (any, error) {
+		return nil, errFailure
+	})
+	clock.Advance(time.Minute + time.Millisecond)
+
+	result, err := cb.Call(func() (any, error) {
+		return 42, nil
+	})
+	if err != nil || result != 42 {
+		t.Fatalf("expected the retry to return 42, got %v, %v", result, err)
+	}
+	if cb.State() != Closed {
+		t.Fatalf("expected a successful retry to close the circuit, got %s", cb.State())
+	}
+	if len(seen) != 2 || seen[0] != Open || seen[1] != Closed {
+		t.Fatalf("expected transitions to open then closed, got %v", seen)
+	}
+}
+
+func TestCircuitBreaker_WithoutHalfOpenRetryFails(t *testing.T) {
+	t.Parallel()
+
+	clock := newFakeClock()
+	cb := NewCircuitBreaker(1, time.Minute, 3, time.Second, WithClock(clock), WithoutHalfOpen())
+	failFn := func() (any, error) {
+		return nil, errFailure
+	}
+
+	_, _ = cb.Call(failFn)
+	clock.Advance(time.Minute + time.Millisecond)
+
+	_, err := cb.Call(failFn)
+	if !errors.Is(err, errFailure) {
+		t.Fatalf("expected the retry to run and fail, got %v", err)
+	}
+	if cb.State() != Open {
+		t.Fatalf("expected a failed retry to keep the circuit open, got %s", cb.State())
+	}
+
+	clock.Advance(30 * time.Second)
+	_, err = cb.Call(func() (any, error) {
+		return 42, nil
+	})
+	if !errors.Is(err, ErrCircuitOpen) {
+		t.Fatalf("expected the failed retry to restart the recovery period, got %v", err)
+	}
+
+	clock.Advance(30*time.Second + time.Millisecond)
+	if _, err := cb.Call(func() (any, error) {
+		return 42, nil
+	}); err != nil {
+		t.Fatalf("expected the next retry to succeed, got %v", err)
+	}
+	if cb.State() != Closed {
+		t.Fatalf("expected the circuit to close, got %s", cb.State())
+	}
+}
+
+func TestCircuitBreaker_WithoutHalfOpenOneRetryAtATime(t *testing.T) {
+	t.Parallel()
+
+	clock := newFakeClock()
+	cb := NewCircuitBreaker(1, time.Minute, 3, time.Second, WithClock(clock), WithoutHalfOpen())
+	_, _ = cb.Call(func() (any, error) {
+		return nil, errFailure
+	})
+	clock.Advance(time.Minute + time.Millisecond)
+
+	started := make(chan struct{})
+	release := make(chan struct{})
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		_, _ = cb.Call(func() (any, error) {
+			close(started)
+			<-release
+			return 42, nil
+		})
+	}()
+	<-started
+
+	_, err := cb.Call(func() (any, error) {
+		return 42, nil
+	})
+	close(release)
+	wg.Wait()
+
+	if !errors.Is(err, ErrCircuitOpen) {
+		t.Fatalf("expected a call during the retry to be rejected, got %v", err)
+	}
+	var openErr *OpenError
+	if !errors.As(err, &openErr) || openErr.RetryAfter() != time.Minute {
+		t.Fatalf("expected the rejection to suggest waiting out a recovery period, got %v", openErr.RetryAfter())
+	}
+	if cb.State() != Closed {
+		t.Fatalf("expected the retry to close the circuit, got %s", cb.State())
+	}
+}
+
+func TestCircuitBreaker_WithoutHalfOpenBackoff(t *testing.T) {
+	t.Parallel()
+
+	clock := newFakeClock()
+	cb := NewCircuitBreaker(1, time.Minute, 1, time.Second, WithClock(clock), WithoutHalfOpen(), WithBackoff(2, time.Hour))
+	failFn := func() (any, error) {
+		return nil, errFailure
+	}
+	openFor := func() time.Duration {
+		return cb.Stats().OpenUntil.Sub(clock.Now())
+	}
+
+	_, _ = cb.Call(failFn)
+	if got := openFor(); got != time.Minute {
+		t.Fatalf("expected the base recovery time after tripping, got %v", got)
+	}
+
+	// Every failed retry doubles the wait
+	for _, want := range []time.Duration{2 * time.Minute, 4 * time.Minute} {
+		clock.Advance(openFor() + time.Millisecond)
+		_, _ = cb.Call(failFn)
+		if got := openFor(); got != want {
+			t.Fatalf("expected a recovery time of %v after a failed retry, got %v", want, got)
+		}
+	}
+
+	// A successful retry resets the backoff
+	clock.Advance(openFor() + time.Millisecond)
+	_, _ = cb.Call(func() (any, error) {
+		return 42, nil
+	})
+	if cb.State() != Closed {
+		t.Fatalf("expected the successful retry to close the circuit, got %s", cb.State())
+	}
+	_, _ = cb.Call(failFn)
+	if got := openFor(); got != time.Minute {
+		t.Fatalf("expected the base recovery time after closing, got %v", got)
+	}
+}
@@ -0,0 +1,18 @@
+package cb
+
+import "time"
+
+// WithWarmup keeps the circuit from tripping for d after the breaker is
+// created, so that the failures of a cold start, like empty caches or
+// dependencies that aren't ready yet, don't open it right away. Failures
+// during the warmup still count, and can trip the circuit as soon as it ends.
+func WithWarmup(d time.Duration) Option {
+	return func(cb *circuitBreaker) {
+		cb.warmup = d
+	}
+}
+
+// warmingUp reports whether the breaker is still within its warmup period
+func (cb *circuitBreaker) warmingUp() bool {
+	return cb.warmup > 0 && cb.clock.Now().Sub(cb.created) < cb.warmup
+}
@@ -0,0 +1,40 @@
+package cb
+
+import (
+	"testing"
+	"time"
+)
+
+func TestCircuitBreaker_Warmup(t *testing.T) {
+	t.Parallel()
+
+	clock := newFakeClock()
+	cb := NewCircuitBreaker(2, time.Minute, 1, time.Second, WithClock(clock), WithWarmup(10*time.Second))
+	failFn := func() (any, error) {
+		return nil, errFailure
+	}
+
+	for i := 0; i < 5; i++ {
+		_, _ = cb.Call(failFn)
+	}
+	if cb.State() != Closed {
+		t.Fatalf("expected failures during the warmup not to trip the circuit, got %s", cb.State())
+	}
+	if got := cb.Counts().ConsecutiveFailures; got != 5 {
+		t.Fatalf("expected the warmup failures to count, got %d", got)
+	}
+
+	_, _ = cb.Call(func() (any, error) {
+		return 42, nil
+	})
+	clock.Advance(10 * time.Second)
+
+	_, _ = cb.Call(failFn)
+	if cb.State() != Closed {
+		t.Fatalf("expected one failure after the warmup to stay below the threshold, got %s", cb.State())
+	}
+	_, _ = cb.Call(failFn)
+	if cb.State() != Open {
+		t.Fatalf("expected failures after the warmup to trip the circuit, got %s", cb.State())
+	}
+}
@@ -0,0 +1,65 @@
+package cb
+
+import "time"
+
+// failureWindow remembers the failures of the last span so that only recent
+// ones count toward the trip threshold
+type failureWindow struct {
+	span     time.Duration     // How long a failure keeps counting
+	failures []windowedFailure // Failures within the span, oldest first
+}
+
+// windowedFailure is a failure remembered by a failureWindow
+type windowedFailure struct {
+	at     time.Time // When the failure happened
+	weight float64   // Weight the failure counts with
+}
+
+// WithFailureWindow trips the circuit on the number of failures within the
+// last window instead of the number of consecutive ones: failures older than
+// window stop counting toward the threshold, but successes no longer reset
+// the count. A zero or negative window restores consecutive counting.
+func WithFailureWindow(window time.Duration) Option {
+	return func(cb *circuitBreaker) {
+		cb.window = nil
+		if window > 0 {
+			cb.window = &failureWindow{span: window}
+		}
+	}
+}
+
+// add records a failure with the given weight at now
+func (w *failureWindow) add(now time.Time, weight float64) {
+	w.prune(now)
+	w.failures = append(w.failures, windowedFailure{at: now, weight: weight})
+}
+
+// prune forgets the failures that fell out of the window by now
+func (w *failureWindow) prune(now time.Time) {
+	cutoff := now.Add(-w.span)
+	i := 0
+	for i < len(w.failures) && !w.failures[i].at.After(cutoff) {
+		i++
+	}
+	w.failures = w.failures[i:]
+}
+
+// score returns the number of failures in the window as of now, or their
+// total weight if weighted
+func (w *failureWindow) score(now time.Time, weighted bool) float64 {
+	w.prune(now)
+	if !weighted {
+		return float64(len(w.failures))
+	}
+
+	var total float64
+	for _, f := range w.failures {
+		total += f.weight
+	}
+	return total
+}
+
+// clear forgets every failure
+func (w *failureWindow) clear() {
+	w.failures = nil
+}
@@ -0,0 +1,98 @@
+package cb
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestCircuitBreaker_FailureWindowForgetsOldFailures(t *testing.T) {
+	t.Parallel()
+
+	clock := newFakeClock()
+	cb := NewCircuitBreaker(3, time.Minute, 1, time.Second, WithClock(clock), WithFailureWindow(10*time.Second))
+
+	failFn := func() (any, error) {
+		return nil, errFailure
+	}
+
+	// Sporadic failures spread wider than the window never add up to a trip
+	for i := 0; i < 5; i++ {
+		_, _ = cb.Call(failFn)
+		clock.Advance(6 * time.Second)
+	}
+	if cb.state != Closed {
+		t.Fatalf("expected state closed with failures spread out, got %s", cb.state)
+	}
+
+	// While the same number close together does
+	for i := 0; i < 3; i++ {
+		_, _ = cb.Call(failFn)
+		clock.Advance(time.Second)
+	}
+	if cb.state != Open {
+		t.Fatalf("expected state open after 3 failures within the window, got %s", cb.state)
+	}
+}
+
+func TestCircuitBreaker_FailureWindowIgnoresSuccesses(t *testing.T) {
+	t.Parallel()
+
+	clock := newFakeClock()
+	cb := NewCircuitBreaker(2, time.Minute, 1, time.Second, WithClock(clock), WithFailureWindow(time.Minute))
+
+	_, _ = cb.Call(func() (any, error) {
+		return nil, errFailure
+	})
+	_, _ = cb.Call(func() (any, error) {
+		return 42, nil
+	})
+	_, _ = cb.Call(func() (any, error) {
+		return nil, errFailure
+	})
+
+	if cb.state != Open {
+		t.Fatalf("expected an intervening success not to reset the window, got %s", cb.state)
+	}
+}
+
+func TestCircuitBreaker_FailureWindowClearedOnTransition(t *testing.T) {
+	t.Parallel()
+
+	clock := newFakeClock()
+	cb := NewCircuitBreaker(2, time.Second, 1, time.Second, WithClock(clock), WithFailureWindow(time.Minute))
+
+	failFn := func() (any, error) {
+		return nil, errFailure
+	}
+	successFn := func() (any, error) {
+		return 42, nil
+	}
+
+	_, _ = cb.Call(failFn)
+	_, _ = cb.Call(failFn)
+	clock.Advance(2 * time.Second)
+	_, _ = cb.Call(successFn) // Moves the circuit to half-open
+	_, _ = cb.Call(successFn) // Closes it
+
+	if cb.state != Closed {
+		t.Fatalf("expected state closed after recovery, got %s", cb.state)
+	}
+
+	// The failures from before the trip no longer count
+	_, _ = cb.Call(failFn)
+	if cb.state != Closed {
+		t.Fatalf("expected a single failure after recovery to keep the circuit closed, got %s", cb.state)
+	}
+}
+
+func TestCircuitBreaker_FailureWindowDescribe(t *testing.T) {
+	t.Parallel()
+
+	cb := NewCircuitBreaker(3, time.Second, 1, time.Second, WithFailureWindow(30*time.Second))
+
+	out := cb.Describe()
+	if !strings.Contains(out, "closed -> open: after 3 failures within 30s") {
+		t.Fatalf("expected the windowed trip condition in the description, got:\n%s", out)
+	}
+}
@@ -18,11 +18,11 @@ func unreliableService() (any, error) {
 }
 
 func main() {
-	cb := cb.NewCircuitBreaker(
-		2,             // Failure threshold
-		2*time.Second, // Recovery time
-		2,             // Half-open max requests
-		2*time.Second, // Half-open max time
+	cb := cb.New(
+		cb.WithFailureThreshold(2),
+		cb.WithRecoveryTime(2*time.Second),
+		cb.WithHalfOpenMaxRequests(2),
+		cb.WithTimeout(2*time.Second),
 	)
 
 	for i := 0; i < 5; i++ {